@@ -0,0 +1,4 @@
+#!/bin/sh
+# Builds ./main with the commit and build time reported by /version.
+# CompileDaemon splits its build command on spaces, so the quoted ldflags live here.
+go build -ldflags "-X csesoc.unsw.edu.au/m/v2/server/health.Commit=${GIT_COMMIT:-dev} -X csesoc.unsw.edu.au/m/v2/server/health.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)" -o main .
@@ -29,7 +29,109 @@ var doc = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/events": {
+        "/api/v1/audit": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuthKey": []
+                    }
+                ],
+                "tags": [
+                    "audit"
+                ],
+                "summary": "Get a page of the recorded changes, newest first",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Hashed zID of the admin who made the changes",
+                        "name": "actor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "RFC3339 time to list changes from, inclusive",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "RFC3339 time to list changes until, exclusive",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 1,
+                        "type": "integer",
+                        "description": "Maximum number of entries to return",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 0,
+                        "type": "integer",
+                        "description": "Number of entries to skip",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/utility.Page"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/audit.Entry"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid limit or offset",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid or missing token",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permissions",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Unable to retrieve audit log from database",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/events": {
             "get": {
                 "tags": [
                     "events"
@@ -46,18 +148,15 @@ var doc = `{
                         }
                     },
                     "500": {
-                        "description": "Internal server error",
-                        "headers": {
-                            "error": {
-                                "type": "string",
-                                "description": "Unable to retrieve events from file"
-                            }
+                        "description": "Unable to retrieve events from file",
+                        "schema": {
+                            "type": "string"
                         }
                     }
                 }
             }
         },
-        "/faq": {
+        "/api/v1/faq": {
             "get": {
                 "tags": [
                     "faq"
@@ -74,27 +173,21 @@ var doc = `{
                         }
                     },
                     "500": {
-                        "description": "Service unavailable",
-                        "headers": {
-                            "error": {
-                                "type": "string",
-                                "description": "Missing questions and/or answer fields"
-                            }
+                        "description": "Missing questions and/or answer fields",
+                        "schema": {
+                            "type": "string"
                         }
                     },
                     "503": {
-                        "description": "Service unavailable",
-                        "headers": {
-                            "error": {
-                                "type": "string",
-                                "description": "Unable to retrieve FAQs"
-                            }
+                        "description": "Unable to retrieve FAQs",
+                        "schema": {
+                            "type": "string"
                         }
                     }
                 }
             }
         },
-        "/mailing/feedback": {
+        "/api/v1/mailing/feedback": {
             "post": {
                 "tags": [
                     "mailing"
@@ -132,18 +225,21 @@ var doc = `{
                         }
                     },
                     "400": {
-                        "description": "Bad request",
-                        "headers": {
-                            "error": {
-                                "type": "string",
-                                "description": "Invalid form"
-                            }
+                        "description": "Invalid form",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "type": "string"
                         }
                     }
                 }
             }
         },
-        "/mailing/general": {
+        "/api/v1/mailing/general": {
             "post": {
                 "tags": [
                     "mailing"
@@ -183,18 +279,21 @@ var doc = `{
                         }
                     },
                     "400": {
-                        "description": "Bad request",
-                        "headers": {
-                            "error": {
-                                "type": "string",
-                                "description": "Invalid form"
-                            }
+                        "description": "Invalid form",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "type": "string"
                         }
                     }
                 }
             }
         },
-        "/mailing/sponsorship": {
+        "/api/v1/mailing/sponsorship": {
             "post": {
                 "tags": [
                     "mailing"
@@ -234,18 +333,21 @@ var doc = `{
                         }
                     },
                     "400": {
-                        "description": "Bad request",
-                        "headers": {
-                            "error": {
-                                "type": "string",
-                                "description": "Invalid form"
-                            }
+                        "description": "Invalid form",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "type": "string"
                         }
                     }
                 }
             }
         },
-        "/resources/preview": {
+        "/api/v1/resources/preview": {
             "get": {
                 "tags": [
                     "resources"
@@ -262,18 +364,15 @@ var doc = `{
                         }
                     },
                     "500": {
-                        "description": "Internal server error",
-                        "headers": {
-                            "error": {
-                                "type": "string",
-                                "description": "Unable to retrieve resources from database"
-                            }
+                        "description": "Unable to retrieve resources from database",
+                        "schema": {
+                            "type": "string"
                         }
                     }
                 }
             }
         },
-        "/social": {
+        "/api/v1/social": {
             "get": {
                 "tags": [
                     "social"
@@ -290,32 +389,26 @@ var doc = `{
                         }
                     },
                     "500": {
-                        "description": "Service unavailable",
-                        "headers": {
-                            "error": {
-                                "type": "string",
-                                "description": "Missing fields"
-                            }
+                        "description": "Missing fields",
+                        "schema": {
+                            "type": "string"
                         }
                     },
                     "503": {
-                        "description": "Service unavailable",
-                        "headers": {
-                            "error": {
-                                "type": "string",
-                                "description": "Unable to retrieve social media links"
-                            }
+                        "description": "Unable to retrieve social media links",
+                        "schema": {
+                            "type": "string"
                         }
                     }
                 }
             }
         },
-        "/sponsors": {
+        "/api/v1/sponsors": {
             "get": {
                 "tags": [
                     "sponsors"
                 ],
-                "summary": "Get a list of sponsors stored",
+                "summary": "Get a page of the sponsors stored, ordered from the highest tier down",
                 "parameters": [
                     {
                         "maximum": 2,
@@ -324,25 +417,79 @@ var doc = `{
                         "description": "Valid sponsor tier, 0-2 inclusive",
                         "name": "tier",
                         "in": "query"
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 1,
+                        "type": "integer",
+                        "description": "Maximum number of sponsors to return",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 0,
+                        "type": "integer",
+                        "description": "Number of sponsors to skip",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Include sponsors whose expiry has passed, admins only",
+                        "name": "includeExpired",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Bearer \u003ctoken\u003e, required to include expired sponsors",
+                        "name": "Authorization",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/sponsor.Sponsor"
-                            }
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/utility.Page"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/sponsor.Sponsor"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid limit or offset",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid or missing token",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Only admins can list expired sponsors",
+                        "schema": {
+                            "type": "string"
                         }
                     },
                     "500": {
-                        "description": "Internal server error",
-                        "headers": {
-                            "error": {
-                                "type": "string",
-                                "description": "Unable to retrieve sponsors from database"
-                            }
+                        "description": "Unable to retrieve sponsors from database",
+                        "schema": {
+                            "type": "string"
                         }
                     }
                 }
@@ -354,7 +501,8 @@ var doc = `{
                     }
                 ],
                 "consumes": [
-                    "Content-Type application/x-www-form-urlencoded"
+                    "application/x-www-form-urlencoded",
+                    "application/json"
                 ],
                 "tags": [
                     "sponsors"
@@ -397,40 +545,68 @@ var doc = `{
                         "name": "detail",
                         "in": "formData",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "URL",
+                        "name": "url",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "RFC3339 time the sponsor stops being listed",
+                        "name": "expiry",
+                        "in": "formData"
                     }
                 ],
                 "responses": {
                     "201": {
                         "description": "Created",
-                        "headers": {
-                            "response": {
-                                "type": "string",
-                                "description": "Sponsor added"
-                            }
+                        "schema": {
+                            "$ref": "#/definitions/sponsor.Sponsor"
                         }
                     },
                     "400": {
-                        "description": "Bad request",
-                        "headers": {
-                            "error": {
-                                "type": "string",
-                                "description": "Invalid form"
-                            }
+                        "description": "Invalid tier, expected one of 0, 1, 2",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid or missing token",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permissions",
+                        "schema": {
+                            "type": "string"
                         }
                     },
                     "409": {
-                        "description": "Conflict",
-                        "headers": {
-                            "error": {
-                                "type": "string",
-                                "description": "Sponsor already exists on database"
-                            }
+                        "description": "Sponsor already exists on database",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Unable to add sponsor to database",
+                        "schema": {
+                            "type": "string"
                         }
                     }
                 }
             }
         },
-        "/sponsors/{name}": {
+        "/api/v1/sponsors/{name}": {
             "get": {
                 "tags": [
                     "sponsors"
@@ -453,26 +629,33 @@ var doc = `{
                         }
                     },
                     "404": {
-                        "description": "Not found",
-                        "headers": {
-                            "error": {
-                                "type": "string",
-                                "description": "No such sponsor"
-                            }
+                        "description": "No such sponsor",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Unable to retrieve sponsor from database",
+                        "schema": {
+                            "type": "string"
                         }
                     }
                 }
             },
-            "delete": {
+            "put": {
                 "security": [
                     {
                         "BearerAuthKey": []
                     }
                 ],
+                "consumes": [
+                    "application/x-www-form-urlencoded",
+                    "application/json"
+                ],
                 "tags": [
                     "sponsors"
                 ],
-                "summary": "Delete a sponsor",
+                "summary": "Update the details of an existing sponsor",
                 "parameters": [
                     {
                         "type": "string",
@@ -487,35 +670,630 @@ var doc = `{
                         "name": "name",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Logo in base64",
+                        "name": "logo",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "maximum": 2,
+                        "minimum": 0,
+                        "type": "integer",
+                        "description": "Valid tier",
+                        "name": "tier",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Detail",
+                        "name": "detail",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "URL",
+                        "name": "url",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "RFC3339 time the sponsor stops being listed",
+                        "name": "expiry",
+                        "in": "formData"
                     }
                 ],
                 "responses": {
-                    "204": {
-                        "description": "No content",
-                        "headers": {
-                            "response": {
-                                "type": "string",
-                                "description": "Sponsor deleted"
-                            }
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/sponsor.Sponsor"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid tier, expected one of 0, 1, 2",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid or missing token",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permissions",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "No such sponsor",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "type": "string"
                         }
                     },
                     "500": {
-                        "description": "Internal server error",
-                        "headers": {
-                            "error": {
-                                "type": "string",
-                                "description": "Unable to delete sponsor from database"
-                            }
+                        "description": "Unable to update sponsor on database",
+                        "schema": {
+                            "type": "string"
                         }
                     }
                 }
-            }
-        }
-    },
-    "definitions": {
-        "events.Event": {
-            "type": "object",
-            "properties": {
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuthKey": []
+                    }
+                ],
+                "tags": [
+                    "sponsors"
+                ],
+                "summary": "Delete a sponsor",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sponsor name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Return the sponsor that would be deleted without deleting it",
+                        "name": "dryRun",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Sponsor that would be deleted, when dryRun is true",
+                        "schema": {
+                            "$ref": "#/definitions/sponsor.Sponsor"
+                        }
+                    },
+                    "204": {
+                        "description": "No content"
+                    },
+                    "401": {
+                        "description": "Invalid or missing token",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permissions",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "No such sponsor",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Unable to delete sponsor from database",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/sponsors/{name}/logo": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuthKey": []
+                    }
+                ],
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "tags": [
+                    "sponsors"
+                ],
+                "summary": "Upload the logo of an existing sponsor",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sponsor name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "file",
+                        "description": "PNG, JPEG, GIF or WebP image of at most 512KB",
+                        "name": "logo",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/sponsor.Sponsor"
+                        }
+                    },
+                    "400": {
+                        "description": "Logo must be at most 512KB",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid or missing token",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permissions",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "No such sponsor",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Unable to store logo",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/users": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuthKey": []
+                    }
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Get a page of the users who have logged in, ordered by first name",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "user",
+                            "admin"
+                        ],
+                        "type": "string",
+                        "description": "Only list users with this role",
+                        "name": "role",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 1,
+                        "type": "integer",
+                        "description": "Maximum number of users to return",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 0,
+                        "type": "integer",
+                        "description": "Number of users to skip",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/utility.Page"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/login.User"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid limit or offset",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid or missing token",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permissions",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Unable to retrieve users from database",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/healthz": {
+            "get": {
+                "tags": [
+                    "health"
+                ],
+                "summary": "Check MongoDB answers a ping",
+                "responses": {
+                    "200": {
+                        "description": "status",
+                        "schema": {
+                            "$ref": "#/definitions/utility.H"
+                        }
+                    },
+                    "503": {
+                        "description": "Unable to reach database",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/livez": {
+            "get": {
+                "tags": [
+                    "health"
+                ],
+                "summary": "Check the process is able to serve requests",
+                "responses": {
+                    "200": {
+                        "description": "status",
+                        "schema": {
+                            "$ref": "#/definitions/utility.H"
+                        }
+                    }
+                }
+            }
+        },
+        "/login": {
+            "post": {
+                "consumes": [
+                    "application/x-www-form-urlencoded"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Exchange a zID and password for an access token and a refresh token",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "zID, e.g. z1234567",
+                        "name": "zid",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "zPass",
+                        "name": "password",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "token and refreshToken",
+                        "schema": {
+                            "$ref": "#/definitions/utility.H"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid credentials",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Unable to log in",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "503": {
+                        "description": "Authentication server unavailable",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/logout": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuthKey": []
+                    }
+                ],
+                "description": "Must be chained after RequireAuth.",
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Log out, revoking every access and refresh token of the user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "response",
+                        "schema": {
+                            "$ref": "#/definitions/utility.H"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid or missing token",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Unable to log out",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/me": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuthKey": []
+                    }
+                ],
+                "description": "Must be chained after RequireAuth.",
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Get the profile of the authenticated user from their token",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "id, firstName and role",
+                        "schema": {
+                            "$ref": "#/definitions/utility.H"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid or missing token",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/metrics": {
+            "get": {
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "metrics"
+                ],
+                "summary": "Get request counts and latencies in the Prometheus text format",
+                "responses": {
+                    "200": {
+                        "description": "Prometheus metrics",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/readyz": {
+            "get": {
+                "description": "Succeeds when MongoDB answers a ping and the server is not shutting down.",
+                "tags": [
+                    "health"
+                ],
+                "summary": "Check the server should be sent traffic",
+                "responses": {
+                    "200": {
+                        "description": "status",
+                        "schema": {
+                            "$ref": "#/definitions/utility.H"
+                        }
+                    },
+                    "503": {
+                        "description": "Shutting down",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/token/refresh": {
+            "post": {
+                "consumes": [
+                    "application/x-www-form-urlencoded"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Exchange a refresh token for a new access token without logging in again",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Refresh token returned by /login",
+                        "name": "refreshToken",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "token",
+                        "schema": {
+                            "$ref": "#/definitions/utility.H"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid or expired refresh token",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Unable to refresh token",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/version": {
+            "get": {
+                "tags": [
+                    "health"
+                ],
+                "summary": "Get the commit, build time and Go version of the running server",
+                "responses": {
+                    "200": {
+                        "description": "commit, buildTime and goVersion",
+                        "schema": {
+                            "$ref": "#/definitions/utility.H"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "audit.Entry": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string"
+                },
+                "actor": {
+                    "description": "hashed zID of the admin",
+                    "type": "string"
+                },
+                "collection": {
+                    "type": "string"
+                },
+                "target": {
+                    "type": "string"
+                },
+                "time": {
+                    "type": "string"
+                }
+            }
+        },
+        "events.Event": {
+            "type": "object",
+            "properties": {
                 "description": {
                     "type": "string"
                 },
@@ -554,6 +1332,21 @@ var doc = `{
                 }
             }
         },
+        "login.User": {
+            "type": "object",
+            "properties": {
+                "firstName": {
+                    "type": "string"
+                },
+                "role": {
+                    "type": "string"
+                },
+                "userID": {
+                    "description": "hex encoded sha256 of the zid",
+                    "type": "string"
+                }
+            }
+        },
         "resources.Resource": {
             "type": "object",
             "required": [
@@ -601,13 +1394,15 @@ var doc = `{
                 "detail",
                 "logo",
                 "name",
-                "tier",
                 "url"
             ],
             "properties": {
                 "detail": {
                     "type": "string"
                 },
+                "expiry": {
+                    "type": "integer"
+                },
                 "logo": {
                     "type": "string"
                 },
@@ -621,6 +1416,34 @@ var doc = `{
                     "type": "string"
                 }
             }
+        },
+        "utility.H": {
+            "type": "object",
+            "additionalProperties": true
+        },
+        "utility.Page": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "object"
+                },
+                "limit": {
+                    "type": "integer"
+                },
+                "offset": {
+                    "type": "integer"
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        }
+    },
+    "securityDefinitions": {
+        "BearerAuthKey": {
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
         }
     }
 }`
@@ -638,7 +1461,7 @@ type swaggerInfo struct {
 var SwaggerInfo = swaggerInfo{
 	Version:     "1.0",
 	Host:        "",
-	BasePath:    "/api/v1",
+	BasePath:    "/",
 	Schemes:     []string{},
 	Title:       "CSESoc Website Swagger API",
 	Description: "Swagger API for the CSESoc Website project.",
@@ -5,27 +5,37 @@
   The echo web server is initialized in the main() function along the servePages() and serveAPI() functions
   that serve the 2 main functions of the backend.
 
-  SwaggerUI is served on http://localhost:1323/swagger/index.html
+  The API spec, generated from the handler annotations by swag and converted to OpenAPI 3,
+  is served on http://localhost:1323/openapi.json and browsable with SwaggerUI on http://localhost:1323/docs
 
   The server itself runs on a subroutine to enable a graceful shutdown, through the use of Go's channels
-  feature, in the case of server errors or manual SIGINT of the application.
+  feature, in the case of server errors, manual SIGINT or a SIGTERM from the container runtime.
 */
 
 package main
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"syscall"
 	"time"
 
 	// Import utilities
 	. "csesoc.unsw.edu.au/m/v2/server"
 
+	"csesoc.unsw.edu.au/m/v2/server/audit"
 	"csesoc.unsw.edu.au/m/v2/server/events"
 	"csesoc.unsw.edu.au/m/v2/server/faq"
+	"csesoc.unsw.edu.au/m/v2/server/health"
 	"csesoc.unsw.edu.au/m/v2/server/login"
 	"csesoc.unsw.edu.au/m/v2/server/mailing"
 	"csesoc.unsw.edu.au/m/v2/server/resources"
@@ -35,11 +45,15 @@ import (
 	_ "csesoc.unsw.edu.au/m/v2/docs"
 
 	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	echoLog "github.com/labstack/gommon/log"
 	echoSwagger "github.com/swaggo/echo-swagger"
+	"github.com/swaggo/swag"
 
 	"github.com/go-playground/validator/v10"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.org/x/crypto/acme/autocert"
 )
 
 type (
@@ -54,6 +68,9 @@ func (cv *CustomValidator) Validate(i interface{}) error {
 	return cv.validator.Struct(i)
 }
 
+// validRequestID - client supplied request IDs are only trusted if they match this
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
 // @title CSESoc Website Swagger API
 // @version 1.0
 // @description Swagger API for the CSESoc Website project.
@@ -62,20 +79,29 @@ func (cv *CustomValidator) Validate(i interface{}) error {
 // @contact.name Project Lead
 // @contact.email projects.website@csesoc.org.au
 
-// @BasePath /api/v1
+// @BasePath /
 
-// @securityDefinitions.bearerAuth BearerAuthKey
+// @securityDefinitions.apikey BearerAuthKey
 // @in header
 // @name Authorization
 func main() {
+	// Load configuration from the environment
+	config, err := LoadConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+	SetDBTimeout(config.DBTimeout)
+	SetPageLimits(config.DefaultPageLimit, config.MaxPageLimit)
+
 	// Create new instance of echo
 	e := echo.New()
 	e.Debug = true
 	// Validator for structs used
 	e.Validator = &CustomValidator{validator: validator.New()}
 
+	serveMiddleware(e, config)
 	servePages(e)
-	serveAPI(e)
+	client := serveAPI(e, config)
 	println("Web server is online :)")
 
 	// Disable the fetch timer until we get access to the actual CSESoc page
@@ -83,15 +109,15 @@ func main() {
 		go events.FetchTimer()
 	}
 
-	// Bind quit to listen to Interrupt signals
-	quit := make(chan os.Signal)
-	signal.Notify(quit, os.Interrupt)
+	// Bind quit to listen to Interrupt and Terminate signals
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
 
 	// Running server on a subroutine enables a graceful shutdown
 	// Reference: https://echo.labstack.com/cookbook/graceful-shutdown
 	go func() {
-		// Start echo instance on 1323 port
-		if err := e.Start(":1323"); err != nil {
+		// Start echo instance on the configured address, :1323 by default
+		if err := startServer(e, config); err != nil {
 			e.Logger.Info("Error: shutting down the server")
 
 			// Send interrupt signal to begin shutdown
@@ -99,12 +125,21 @@ func main() {
 		}
 	}()
 
+	// Redirect plain HTTP to HTTPS, also answering Let's Encrypt's challenges
+	var redirectServer *http.Server
+	if config.TLSEnabled() && config.HTTPRedirectAddress != "" {
+		redirectServer = serveRedirect(e, config)
+	}
+
 	///////////
 	// SHUTDOWN
 	///////////
 
 	// Wait for interrupt signal
 	<-quit
+	// Fail readiness checks and keep serving until load balancers have stopped sending traffic
+	health.StartDraining()
+	time.Sleep(config.ShutdownDrainDelay)
 	// Dispatch bundles on shutdown
 	if !DEVELOPMENT {
 		mailing.DispatchEnquiryBundles()
@@ -114,16 +149,138 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 	if err := e.Shutdown(ctx); err != nil {
+		e.Logger.Error(err)
+	}
+	if redirectServer != nil {
+		if err := redirectServer.Shutdown(ctx); err != nil {
+			e.Logger.Error(err)
+		}
+	}
+	// Close the MongoDB connections once in-flight requests are done
+	if err := client.Disconnect(ctx); err != nil {
 		e.Logger.Fatal(err)
 	}
 }
 
+func serveMiddleware(e *echo.Echo, config *Config) {
+
+	println("Serving middleware...")
+
+	levels := map[string]echoLog.Lvl{
+		"debug": echoLog.DEBUG,
+		"info":  echoLog.INFO,
+		"warn":  echoLog.WARN,
+		"error": echoLog.ERROR,
+		"off":   echoLog.OFF,
+	}
+	e.Logger.SetLevel(levels[config.LogLevel])
+
+	// Send every error in the same envelope
+	e.HTTPErrorHandler = HTTPErrorHandler
+
+	// Identify clients for rate limiting and logs without trusting headers they can forge
+	e.IPExtractor = ClientIPExtractor(config.TrustedProxies)
+
+	// Tag each request with an X-Request-ID, reusing the one sent by the client
+	// if it is well formed so log lines can be correlated across services
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			header := c.Request().Header
+			if !validRequestID.MatchString(header.Get(echo.HeaderXRequestID)) {
+				header.Del(echo.HeaderXRequestID)
+			}
+			return next(c)
+		}
+	})
+	e.Use(middleware.RequestID())
+
+	// Log every request as a JSON line. Only the path is logged as query strings
+	// may carry credentials, and no headers are logged so tokens never appear.
+	e.Use(middleware.LoggerWithConfig(middleware.LoggerConfig{
+		Skipper: func(c echo.Context) bool {
+			return e.Logger.Level() > echoLog.INFO
+		},
+		Format: `{"time":"${time_rfc3339_nano}","id":"${id}","remote_ip":"${remote_ip}",` +
+			`"method":"${method}","path":"${path}","status":${status},"error":"${error}",` +
+			`"latency":${latency},"latency_human":"${latency_human}"}` + "\n",
+	}))
+
+	// Turn panics in handlers into a plain 500 so clients never see a stack trace,
+	// logging the stack with the request ID so it can be matched to the request
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) (err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					stack := make([]byte, 4<<10)
+					length := runtime.Stack(stack, false)
+					c.Logger().Errorf("Recovered from panic in request %s: %v\n%s", RequestID(c), r, stack[:length])
+					err = Internal("internal server error")
+				}
+			}()
+			return next(c)
+		}
+	})
+
+	// Send security headers with every response. SwaggerUI relies on inline
+	// scripts the CSP would block, so the docs are left out.
+	e.Use(middleware.SecureWithConfig(middleware.SecureConfig{
+		Skipper: func(c echo.Context) bool {
+			path := c.Request().URL.Path
+			return strings.HasPrefix(path, "/docs") || strings.HasPrefix(path, "/swagger/")
+		},
+		XSSProtection:         "1; mode=block",
+		ContentTypeNosniff:    "nosniff",
+		XFrameOptions:         "DENY",
+		HSTSMaxAge:            config.HSTSMaxAge,
+		ContentSecurityPolicy: config.ContentSecurityPolicy,
+		ReferrerPolicy:        "strict-origin-when-cross-origin",
+	}))
+
+	// Compress responses large enough to benefit from it
+	e.Use(Gzip(config.GzipMinLength))
+
+	// Cap the size of request bodies on write routes, answering 413 past the limit
+	e.Use(middleware.BodyLimitWithConfig(middleware.BodyLimitConfig{
+		Skipper: func(c echo.Context) bool {
+			switch c.Request().Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				return true
+			}
+			return false
+		},
+		Limit: config.BodyLimit,
+	}))
+
+	// Record request counts and latencies, served from /metrics
+	e.Use(Metrics())
+
+	// Limit how fast a single client can make requests. Routes can add a
+	// stricter RateLimit of their own on top of this one.
+	if config.RateLimitRate > 0 {
+		e.Use(RateLimitWithConfig(RateLimitConfig{
+			Skipper: func(c echo.Context) bool {
+				for _, probe := range PROBE_URLS {
+					if c.Request().URL.Path == "/"+probe {
+						return true
+					}
+				}
+				return false
+			},
+			Rate:  config.RateLimitRate,
+			Burst: config.RateLimitBurst,
+		}))
+	}
+}
+
+// distDir - location of the frontend build
+const distDir = "./dist/"
+
 func servePages(e *echo.Echo) {
 
 	println("Serving pages...")
 
 	// Setup our assetHandler and point it to our static build location
-	assetHandler := http.FileServer(http.Dir("./dist/"))
+	assetHandler := http.FileServer(http.Dir(distDir))
 
 	// Setup a new echo route to load the build as our base path
 	e.GET("/", echo.WrapHandler(assetHandler))
@@ -134,13 +291,29 @@ func servePages(e *echo.Echo) {
 	e.GET("/img/*", echo.WrapHandler(assetHandler))
 	e.GET("/fonts/*", echo.WrapHandler(assetHandler))
 
+	// Unknown routes under /api are answered in JSON for API clients, and
+	// anything else falls back to the SPA so client side routing can handle it
 	echo.NotFoundHandler = func(c echo.Context) error {
-		// TODO: Render your 404 page
-		return c.String(http.StatusNotFound, "not found page")
+		path := c.Request().URL.Path
+		if strings.HasPrefix(path, "/api/") || c.Request().Method != http.MethodGet {
+			return NotFound("No such endpoint: " + path)
+		}
+		return c.File(filepath.Join(distDir, "index.html"))
 	}
+	e.Any("/api/*", echo.NotFoundHandler)
+
+	// Any other GET is a page of the SPA, so serve files from the build if they
+	// exist and index.html otherwise to let client side routing take over on refresh
+	e.GET("/*", func(c echo.Context) error {
+		path := filepath.Join(distDir, filepath.Clean("/"+c.Request().URL.Path))
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return echo.WrapHandler(assetHandler)(c)
+		}
+		return echo.NotFoundHandler(c)
+	})
 }
 
-func serveAPI(e *echo.Echo) {
+func serveAPI(e *echo.Echo, config *Config) *mongo.Client {
 
 	////////////////
 	// MongoDB Setup
@@ -149,14 +322,14 @@ func serveAPI(e *echo.Echo) {
 	println("Initialising MongoDB...")
 
 	// Set client options
-	clientOptions := options.Client().ApplyURI("mongodb://mongo:27017")
-	// Connect to MongoDB
-	client, err := mongo.Connect(context.TODO(), clientOptions)
-	if err != nil {
-		log.Fatal(err)
-	}
-	// Check connection
-	err = client.Ping(context.TODO(), nil)
+	clientOptions := options.Client().
+		ApplyURI(config.MongoURI).
+		SetMaxPoolSize(config.MongoMaxPoolSize).
+		SetMinPoolSize(config.MongoMinPoolSize).
+		SetMaxConnIdleTime(config.MongoMaxConnIdleTime).
+		SetPoolMonitor(DBPoolMonitor)
+	// Connect to MongoDB, waiting for it to come up if it is still starting
+	client, err := connectMongo(clientOptions, config.MongoConnectAttempts, config.MongoConnectTimeout)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -167,8 +340,26 @@ func serveAPI(e *echo.Echo) {
 
 	if DEVELOPMENT {
 		println("Serving Swagger...")
-		// SwaggerUI can be accessed from: http://localhost:1323/swagger/index.html
-		e.GET("/swagger/*", echoSwagger.WrapHandler)
+		e.GET("/openapi.json", func(c echo.Context) error {
+			doc, err := swag.ReadDoc()
+			if err != nil {
+				return err
+			}
+			// swag generates Swagger 2.0, which is served as OpenAPI 3
+			spec, err := OpenAPI3([]byte(doc))
+			if err != nil {
+				return err
+			}
+			return c.Blob(http.StatusOK, echo.MIMEApplicationJSONCharsetUTF8, spec)
+		})
+		// SwaggerUI can be accessed from: http://localhost:1323/docs
+		docsHandler := echoSwagger.EchoWrapHandler(echoSwagger.URL("/openapi.json"))
+		e.GET("/docs", func(c echo.Context) error {
+			return c.Redirect(http.StatusMovedPermanently, "/docs/index.html")
+		})
+		e.GET("/docs/*", docsHandler)
+		// Kept for links to the old location
+		e.GET("/swagger/*", docsHandler)
 	}
 
 	///////////////////////
@@ -177,26 +368,53 @@ func serveAPI(e *echo.Echo) {
 
 	println("Serving API...")
 
+	// HEALTH
+	health.Setup(client)
+	e.GET("/healthz", health.HandleHealthz)
+	e.GET("/livez", health.HandleLivez)
+	e.GET("/readyz", health.HandleReadyz)
+	e.GET("/version", health.HandleVersion)
+	e.GET("/metrics", HandleMetrics)
+
 	// AUTHENTICATION
-	e.POST("/login", login.TempLogin)
+	login.Setup(client, config)
+	// Keyed on the client IP from e.IPExtractor, so it can't be reset with X-Forwarded-For
+	loginLimit := RateLimit(config.LoginRateLimit, config.LoginRateWindow)
+	e.POST("/login", login.HandleLogin, loginLimit)
+	e.POST("/token/refresh", login.HandleRefresh)
+	e.POST("/logout", login.HandleLogout, login.RequireAuth())
+	e.GET("/me", login.HandleMe, login.RequireAuth())
 
 	v1 := e.Group("/api/v1")
 	{
+		// AUDIT
+		audit.Setup(client)
+		v1.GET("/audit", audit.HandleGetMultiple, login.RequireAuth(), login.RequireRole(login.RoleAdmin))
+
+		// USERS
+		v1.GET("/users", login.HandleGetUsers, login.RequireAuth(), login.RequireRole(login.RoleAdmin))
+
 		// SPONSORS
-		sponsor.Setup(client)
+		sponsor.Setup(client, config)
+		e.Static("/"+LOGO_URL, config.LogoDir)
+		if config.SponsorPurgeAfter > 0 {
+			go sponsor.PurgeTimer(config.SponsorPurgeAfter)
+		}
 		sponsorsAPI := v1.Group("/sponsors")
 		{
 			sponsorsAPI.GET("/:name", sponsor.HandleGetSingle)
-			sponsorsAPI.POST("", sponsor.HandleNew)
-			sponsorsAPI.DELETE("/:name", sponsor.HandleDelete)
-			// sponsorsAPI.POST("", sponsor.HandleNew, middleware.JWT(JWT_SECRET))
-			// sponsorsAPI.DELETE("/:name", sponsor.HandleDelete, middleware.JWT(JWT_SECRET))
-			sponsorsAPI.GET("", sponsor.HandleGetMultiple)
+			sponsorsAPI.POST("", sponsor.HandleNew, login.RequireAuth(), login.RequireRole(login.RoleAdmin))
+			sponsorsAPI.PUT("/:name", sponsor.HandleUpdate, login.RequireAuth(), login.RequireRole(login.RoleAdmin))
+			sponsorsAPI.DELETE("/:name", sponsor.HandleDelete, login.RequireAuth(), login.RequireRole(login.RoleAdmin))
+			if sponsor.LogoUploadsEnabled() {
+				sponsorsAPI.POST("/:name/logo", sponsor.HandleUploadLogo, login.RequireAuth(), login.RequireRole(login.RoleAdmin))
+			}
+			sponsorsAPI.GET("", sponsor.HandleGetMultiple, login.OptionalAuth())
 		}
 
 		// MAILING
 		mailing.Setup()
-		mailingAPI := v1.Group("/mailing")
+		mailingAPI := v1.Group("/mailing", RateLimit(config.MailingRateLimit, config.MailingRateWindow))
 		{
 			mailingAPI.POST("/general", mailing.HandleGeneralMessage)
 			mailingAPI.POST("/sponsorship", mailing.HandleSponsorshipMessage)
@@ -230,4 +448,75 @@ func serveAPI(e *echo.Echo) {
 			resourcesAPI.GET("/preview", resources.HandleGetPreview)
 		}
 	}
+
+	return client
+}
+
+// startServer - serves HTTPS with the configured certificate or one from Let's Encrypt,
+// falling back to plain HTTP for local development
+func startServer(e *echo.Echo, config *Config) error {
+	switch {
+	case config.TLSCertFile != "":
+		return e.StartTLS(config.Address, config.TLSCertFile, config.TLSKeyFile)
+	case len(config.AutoTLSHosts) > 0:
+		e.AutoTLSManager.HostPolicy = autocert.HostWhitelist(config.AutoTLSHosts...)
+		e.AutoTLSManager.Cache = autocert.DirCache(config.AutoTLSCacheDir)
+		return e.StartAutoTLS(config.Address)
+	default:
+		return e.Start(config.Address)
+	}
+}
+
+// serveRedirect - listens on the redirect address for plain HTTP, redirecting every
+// request to the same host on the port HTTPS is served on
+func serveRedirect(e *echo.Echo, config *Config) *http.Server {
+	_, httpsPort, err := net.SplitHostPort(config.Address)
+	if err != nil {
+		e.Logger.Fatal(err)
+	}
+	redirect := echo.New()
+	redirect.Pre(HTTPSRedirect(httpsPort))
+
+	server := &http.Server{
+		Addr:    config.HTTPRedirectAddress,
+		Handler: e.AutoTLSManager.HTTPHandler(redirect),
+	}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			e.Logger.Error(err)
+		}
+	}()
+	return server
+}
+
+// connectMongo - connects and pings MongoDB, retrying with exponential backoff
+// until it answers or the attempts run out
+func connectMongo(clientOptions *options.ClientOptions, attempts int, timeout time.Duration) (*mongo.Client, error) {
+	const maxBackoff = 30 * time.Second
+	backoff := time.Second
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		var client *mongo.Client
+		client, err = mongo.Connect(context.Background(), clientOptions)
+		if err == nil {
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			err = client.Ping(ctx, nil)
+			cancel()
+			if err == nil {
+				return client, nil
+			}
+			client.Disconnect(context.Background())
+		}
+
+		if attempt >= attempts {
+			return nil, fmt.Errorf("Could not connect to MongoDB after %d attempts: %v", attempts, err)
+		}
+		log.Printf("Could not connect to MongoDB (attempt %d of %d), retrying in %v: %v", attempt, attempts, backoff, err)
+		time.Sleep(backoff)
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
 }
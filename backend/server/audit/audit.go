@@ -0,0 +1,165 @@
+/*
+  Audit
+  --
+  This module keeps a record of the changes admins make, so it can be traced who
+  changed what and when. Handlers call Record once a mutation has succeeded, and
+  admins can browse the record from the API.
+*/
+
+package audit
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	. "csesoc.unsw.edu.au/m/v2/server"
+	"csesoc.unsw.edu.au/m/v2/server/login"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Entry - struct to contain a single recorded change
+type Entry struct {
+	Actor      string    `bson:"actor" json:"actor"` // hashed zID of the admin
+	Action     string    `bson:"action" json:"action"`
+	Collection string    `bson:"collection" json:"collection"`
+	Target     string    `bson:"target" json:"target"`
+	Time       time.Time `bson:"time" json:"time"`
+}
+
+// Actions that can be recorded
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+)
+
+var auditColl *mongo.Collection
+
+////////
+// SETUP
+////////
+
+// Setup - setup the collection to be used for the audit log
+func Setup(client *mongo.Client) {
+	auditColl = client.Database("csesoc").Collection("audit")
+
+	// Creating indexes for listing everything, or one actor's changes, newest first
+	indexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "time", Value: -1}},
+			Options: options.Index().SetName("time_-1"),
+		},
+		{
+			Keys:    bson.D{{Key: "actor", Value: 1}, {Key: "time", Value: -1}},
+			Options: options.Index().SetName("actor_1_time_-1"),
+		},
+	}
+	if err := EnsureIndexes(auditColl, indexes); err != nil {
+		log.Fatal("Could not create index: ", err)
+	}
+}
+
+///////////
+// HANDLERS
+///////////
+
+// HandleGetMultiple godoc
+// @Summary Get a page of the recorded changes, newest first
+// @Tags audit
+// @Param Authorization header string true "Bearer <token>"
+// @Param actor query string false "Hashed zID of the admin who made the changes"
+// @Param from query string false "RFC3339 time to list changes from, inclusive"
+// @Param to query string false "RFC3339 time to list changes until, exclusive"
+// @Param limit query integer false "Maximum number of entries to return" mininum(1) maxinum(100)
+// @Param offset query integer false "Number of entries to skip" mininum(0)
+// @Success 200 {object} utility.Page{data=[]Entry}
+// @Failure 400 {string} error "Invalid from or to, expected an RFC3339 time such as 2006-01-02T15:04:05Z"
+// @Failure 400 {string} error "Invalid limit or offset"
+// @Failure 401 {string} error "Invalid or missing token"
+// @Failure 403 {string} error "Insufficient permissions"
+// @Failure 500 {string} error "Unable to retrieve audit log from database"
+// @Router /api/v1/audit [get]
+// @Security BearerAuthKey
+func HandleGetMultiple(c echo.Context) error {
+	filter := bson.D{}
+	if actor := c.QueryParam("actor"); actor != "" {
+		filter = append(filter, bson.E{Key: "actor", Value: actor})
+	}
+
+	timeRange := bson.D{}
+	for _, bound := range []struct{ param, operator string }{{"from", "$gte"}, {"to", "$lt"}} {
+		value := c.QueryParam(bound.param)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return BadRequest("Invalid from or to, expected an RFC3339 time such as 2006-01-02T15:04:05Z")
+		}
+		timeRange = append(timeRange, bson.E{Key: bound.operator, Value: t})
+	}
+	if len(timeRange) > 0 {
+		filter = append(filter, bson.E{Key: "time", Value: timeRange})
+	}
+
+	limit, offset, err := ParsePagination(c)
+	if err != nil {
+		return BadRequest("Invalid limit or offset")
+	}
+
+	ctx, cancel := DBContext(c)
+	defer cancel()
+	total, err := auditColl.CountDocuments(ctx, filter)
+	if err != nil {
+		return Internal("Unable to retrieve audit log from database")
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "time", Value: -1}}).
+		SetLimit(limit).
+		SetSkip(offset)
+	cursor, err := auditColl.Find(ctx, filter, opts)
+	if err != nil {
+		return Internal("Unable to retrieve audit log from database")
+	}
+	entries := []Entry{}
+	if err := cursor.All(ctx, &entries); err != nil {
+		return Internal("Unable to retrieve audit log from database")
+	}
+
+	return c.JSON(http.StatusOK, Page{
+		Data:   entries,
+		Total:  total,
+		Limit:  limit,
+		Offset: offset,
+	})
+}
+
+//////////
+// HELPERS
+//////////
+
+// Record - records that the authenticated caller made a change to target in collection.
+// The change has already been made, so failing to record it is logged rather than returned.
+func Record(c echo.Context, action string, collection string, target string) {
+	entry := Entry{
+		Action:     action,
+		Collection: collection,
+		Target:     target,
+		Time:       time.Now().UTC(),
+	}
+	if claims, ok := login.GetClaims(c); ok {
+		entry.Actor = claims.HashedZID
+	}
+
+	// The change has been made, so record it even if the client has gone
+	ctx, cancel := BackgroundDBContext()
+	defer cancel()
+	if _, err := auditColl.InsertOne(ctx, entry); err != nil {
+		c.Logger().Errorf("Could not record %s of %s %q in request %s: %v", action, collection, target, RequestID(c), err)
+	}
+}
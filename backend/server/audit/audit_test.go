@@ -0,0 +1,51 @@
+package audit
+
+import (
+	"net/http"
+	"testing"
+
+	. "csesoc.unsw.edu.au/m/v2/server"
+)
+
+func TestAudit(t *testing.T) {
+	get := func(query string, authorization string) (*http.Response, error) {
+		req, _ := http.NewRequest("GET", BASE_URL+AUDIT_URL+query, nil)
+		if authorization != "" {
+			req.Header.Add("Authorization", authorization)
+		}
+		return http.DefaultClient.Do(req)
+	}
+
+	t.Run("Audit log requires a token", func(t *testing.T) {
+		resp, err := get("", "")
+		if err != nil {
+			t.Errorf("Could not perform GET request: %v", err)
+			return
+		}
+		defer resp.Body.Close()
+
+		AssertStatus(t, resp.StatusCode, http.StatusUnauthorized)
+	})
+
+	t.Run("Admins can list the audit log", func(t *testing.T) {
+		resp, err := get("?from=2020-01-01T00:00:00Z", AUTH_TOKEN)
+		if err != nil {
+			t.Errorf("Could not perform GET request: %v", err)
+			return
+		}
+		defer resp.Body.Close()
+
+		AssertStatus(t, resp.StatusCode, http.StatusOK)
+	})
+
+	t.Run("Invalid date range", func(t *testing.T) {
+		resp, err := get("?to=yesterday", AUTH_TOKEN)
+		if err != nil {
+			t.Errorf("Could not perform GET request: %v", err)
+			return
+		}
+		defer resp.Body.Close()
+
+		AssertStatus(t, resp.StatusCode, http.StatusBadRequest)
+	})
+}
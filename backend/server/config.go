@@ -0,0 +1,374 @@
+/*
+  Config
+  --
+  This file loads the runtime configuration of the server from environment variables.
+  Every setting has a default suited to local development, except for those that must
+  never be shared between deployments, which are always required. Local setups can opt
+  in to a fixed JWT secret with ALLOW_DEV_JWT_SECRET=1.
+*/
+
+package utility
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/gommon/bytes"
+)
+
+// Config - runtime settings read from the environment
+type Config struct {
+	// Address the server listens on, from HOST and PORT
+	Address   string
+	MongoURI  string
+	JWTSecret []byte
+	LDAPHost  string
+	DBTimeout time.Duration
+	LogLevel  string
+	// Accept the public test accounts instead of LDAP, never enable in production
+	TempLogin bool
+	// zIDs stored as admins on startup, the only way to create the first admin
+	AdminZIDs []string
+	// Size and idle timeout of the MongoDB connection pool
+	MongoMaxPoolSize     uint64
+	MongoMinPoolSize     uint64
+	MongoMaxConnIdleTime time.Duration
+	// Attempts to reach MongoDB on startup and how long to wait for each
+	MongoConnectAttempts int
+	MongoConnectTimeout  time.Duration
+	// How long after expiring sponsors are deleted, 0 keeps them forever
+	SponsorPurgeAfter time.Duration
+	// Requests per second allowed from one IP across the API, 0 disables the limit
+	RateLimitRate  float64
+	RateLimitBurst int
+	// Proxies whose X-Forwarded-For is trusted to identify clients, none by default
+	TrustedProxies []*net.IPNet
+	// Directory uploaded sponsor logos are stored in
+	LogoDir string
+	// Content-Security-Policy sent with every response, see defaultCSP
+	ContentSecurityPolicy string
+	// Seconds browsers should only use HTTPS for, 0 doesn't send HSTS
+	HSTSMaxAge int
+	// Smallest response compressed with gzip, in bytes
+	GzipMinLength int
+	// Largest request body accepted on write routes, e.g. "1M"
+	BodyLimit string
+	// How long /readyz fails for before the server stops accepting connections
+	ShutdownDrainDelay time.Duration
+	// Page size of list endpoints when no limit is asked for, and the largest allowed
+	DefaultPageLimit int64
+	MaxPageLimit     int64
+	// Login attempts allowed from one IP per window
+	LoginRateLimit  int
+	LoginRateWindow time.Duration
+	// Messages allowed from one IP through the mailing forms per window, 0 disables the limit
+	MailingRateLimit  int
+	MailingRateWindow time.Duration
+	// Certificate and key to serve HTTPS with, unset serves plain HTTP
+	TLSCertFile string
+	TLSKeyFile  string
+	// Domains to fetch certificates for from Let's Encrypt instead, and where to keep them
+	AutoTLSHosts    []string
+	AutoTLSCacheDir string
+	// Address to redirect plain HTTP to HTTPS from when serving HTTPS, e.g. ":80"
+	HTTPRedirectAddress string
+}
+
+// logLevels - accepted values for LOG_LEVEL, from most to least verbose
+var logLevels = []string{"debug", "info", "warn", "error", "off"}
+
+// defaultCSP - allows the frontend's own assets plus the CDNs, icons and embeds it loads
+const defaultCSP = "default-src 'self'; " +
+	"script-src 'self' https://kit.fontawesome.com https://connect.facebook.net; " +
+	"style-src 'self' 'unsafe-inline' https://cdn.jsdelivr.net https://ka-f.fontawesome.com; " +
+	"font-src 'self' data: https://cdn.jsdelivr.net https://ka-f.fontawesome.com; " +
+	"img-src 'self' data: https:; " +
+	"connect-src 'self' https://ka-f.fontawesome.com; " +
+	"frame-src https://www.facebook.com https://www.youtube.com; " +
+	"object-src 'none'; base-uri 'self'; frame-ancestors 'none'"
+
+// devJWTSecret - signing key used when JWT_SECRET is unset and ALLOW_DEV_JWT_SECRET=1.
+// It is public, so anyone can forge tokens signed with it.
+const devJWTSecret = "development_secret"
+
+// LoadConfig - reads the server configuration from environment variables
+func LoadConfig() (*Config, error) {
+	config := &Config{
+		MongoURI:  getEnv("MONGO_URI", "mongodb://mongo:27017"),
+		LDAPHost:  getEnv("LDAP_HOST", "ad.unsw.edu.au:389"),
+		LogLevel:  strings.ToLower(getEnv("LOG_LEVEL", "info")),
+		BodyLimit: getEnv("BODY_LIMIT", "1M"),
+		LogoDir:   getEnv("LOGO_DIR", "uploads/logos"),
+
+		ContentSecurityPolicy: getEnv("CONTENT_SECURITY_POLICY", defaultCSP),
+	}
+
+	port, err := strconv.Atoi(getEnv("PORT", "1323"))
+	if err != nil || port < 1 || port > 65535 {
+		return nil, fmt.Errorf("Invalid PORT %q, expected a number from 1 to 65535", os.Getenv("PORT"))
+	}
+	config.Address = net.JoinHostPort(getEnv("HOST", ""), strconv.Itoa(port))
+
+	config.TLSCertFile = getEnv("TLS_CERT_FILE", "")
+	config.TLSKeyFile = getEnv("TLS_KEY_FILE", "")
+	if (config.TLSCertFile == "") != (config.TLSKeyFile == "") {
+		return nil, fmt.Errorf("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	if hosts := getEnv("AUTOTLS_HOSTS", ""); hosts != "" {
+		if config.TLSCertFile != "" {
+			return nil, fmt.Errorf("AUTOTLS_HOSTS cannot be used with TLS_CERT_FILE")
+		}
+		for _, host := range strings.Split(hosts, ",") {
+			config.AutoTLSHosts = append(config.AutoTLSHosts, strings.TrimSpace(host))
+		}
+	}
+	config.AutoTLSCacheDir = getEnv("AUTOTLS_CACHE_DIR", "certs")
+	config.HTTPRedirectAddress = getEnv("HTTP_REDIRECT_ADDR", "")
+
+	if !contains(logLevels, config.LogLevel) {
+		return nil, fmt.Errorf("Invalid LOG_LEVEL %q, expected one of %v", config.LogLevel, logLevels)
+	}
+
+	if _, err := bytes.Parse(config.BodyLimit); err != nil {
+		return nil, fmt.Errorf("Invalid BODY_LIMIT %q: %v", config.BodyLimit, err)
+	}
+
+	maxPoolSize, err := getEnvInt("MONGO_MAX_POOL_SIZE", 100)
+	if err != nil {
+		return nil, err
+	}
+	minPoolSize, err := getEnvInt("MONGO_MIN_POOL_SIZE", 5)
+	if err != nil {
+		return nil, err
+	}
+	if minPoolSize < 0 || maxPoolSize < 1 || minPoolSize > maxPoolSize {
+		return nil, fmt.Errorf("Invalid MongoDB pool size, expected 0 <= MONGO_MIN_POOL_SIZE (%d) <= MONGO_MAX_POOL_SIZE (%d) and MONGO_MAX_POOL_SIZE >= 1", minPoolSize, maxPoolSize)
+	}
+	config.MongoMaxPoolSize = uint64(maxPoolSize)
+	config.MongoMinPoolSize = uint64(minPoolSize)
+
+	maxConnIdleTime, err := getEnvDuration("MONGO_MAX_CONN_IDLE_TIME", 5*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	config.MongoMaxConnIdleTime = maxConnIdleTime
+
+	connectAttempts, err := getEnvInt("MONGO_CONNECT_ATTEMPTS", 6)
+	if err != nil {
+		return nil, err
+	}
+	if connectAttempts < 1 {
+		return nil, fmt.Errorf("Invalid MONGO_CONNECT_ATTEMPTS %d, expected at least 1", connectAttempts)
+	}
+	config.MongoConnectAttempts = connectAttempts
+
+	connectTimeout, err := getEnvDuration("MONGO_CONNECT_TIMEOUT", 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	config.MongoConnectTimeout = connectTimeout
+
+	hstsMaxAge, err := getEnvInt("HSTS_MAX_AGE", 0)
+	if err != nil {
+		return nil, err
+	}
+	if hstsMaxAge < 0 {
+		return nil, fmt.Errorf("Invalid HSTS_MAX_AGE %d, expected at least 0", hstsMaxAge)
+	}
+	config.HSTSMaxAge = hstsMaxAge
+
+	gzipMinLength, err := getEnvInt("GZIP_MIN_LENGTH", 1024)
+	if err != nil {
+		return nil, err
+	}
+	if gzipMinLength < 0 {
+		return nil, fmt.Errorf("Invalid GZIP_MIN_LENGTH %d, expected at least 0", gzipMinLength)
+	}
+	config.GzipMinLength = gzipMinLength
+
+	maxPageLimit, err := getEnvInt("PAGE_LIMIT_MAX", MAX_PAGE_LIMIT)
+	if err != nil {
+		return nil, err
+	}
+	if maxPageLimit < 1 {
+		return nil, fmt.Errorf("Invalid PAGE_LIMIT_MAX %d, expected at least 1", maxPageLimit)
+	}
+	defaultPageLimit, err := getEnvInt("PAGE_LIMIT_DEFAULT", DEFAULT_PAGE_LIMIT)
+	if err != nil {
+		return nil, err
+	}
+	if defaultPageLimit < 1 || defaultPageLimit > maxPageLimit {
+		return nil, fmt.Errorf("Invalid PAGE_LIMIT_DEFAULT %d, expected 1 to PAGE_LIMIT_MAX (%d)", defaultPageLimit, maxPageLimit)
+	}
+	config.DefaultPageLimit = int64(defaultPageLimit)
+	config.MaxPageLimit = int64(maxPageLimit)
+
+	dbTimeout, err := getEnvDuration("DB_TIMEOUT", 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	config.DBTimeout = dbTimeout
+
+	shutdownDrainDelay, err := getEnvDuration("SHUTDOWN_DRAIN_DELAY", 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	config.ShutdownDrainDelay = shutdownDrainDelay
+
+	sponsorPurgeAfter, err := getEnvDuration("SPONSOR_PURGE_AFTER", 0)
+	if err != nil {
+		return nil, err
+	}
+	config.SponsorPurgeAfter = sponsorPurgeAfter
+
+	rateLimitRate, err := getEnvFloat("RATE_LIMIT_RPS", 20)
+	if err != nil {
+		return nil, err
+	}
+	if rateLimitRate < 0 {
+		return nil, fmt.Errorf("Invalid RATE_LIMIT_RPS %v, expected at least 0", rateLimitRate)
+	}
+	config.RateLimitRate = rateLimitRate
+
+	rateLimitBurst, err := getEnvInt("RATE_LIMIT_BURST", 40)
+	if err != nil {
+		return nil, err
+	}
+	if rateLimitBurst < 1 {
+		return nil, fmt.Errorf("Invalid RATE_LIMIT_BURST %d, expected at least 1", rateLimitBurst)
+	}
+	config.RateLimitBurst = rateLimitBurst
+
+	if proxies := getEnv("TRUSTED_PROXIES", ""); proxies != "" {
+		for _, cidr := range strings.Split(proxies, ",") {
+			_, ipNet, err := net.ParseCIDR(strings.TrimSpace(cidr))
+			if err != nil {
+				return nil, fmt.Errorf("Invalid TRUSTED_PROXIES %q, expected comma separated CIDRs", proxies)
+			}
+			config.TrustedProxies = append(config.TrustedProxies, ipNet)
+		}
+	}
+
+	loginRateLimit, err := getEnvInt("LOGIN_RATE_LIMIT", 10)
+	if err != nil {
+		return nil, err
+	}
+	if loginRateLimit < 1 {
+		return nil, fmt.Errorf("Invalid LOGIN_RATE_LIMIT %d, expected at least 1", loginRateLimit)
+	}
+	config.LoginRateLimit = loginRateLimit
+
+	loginRateWindow, err := getEnvDuration("LOGIN_RATE_WINDOW", time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	if loginRateWindow <= 0 {
+		return nil, fmt.Errorf("Invalid LOGIN_RATE_WINDOW %v, expected a positive duration", loginRateWindow)
+	}
+	config.LoginRateWindow = loginRateWindow
+
+	mailingRateLimit, err := getEnvInt("MAILING_RATE_LIMIT", 5)
+	if err != nil {
+		return nil, err
+	}
+	if mailingRateLimit < 0 {
+		return nil, fmt.Errorf("Invalid MAILING_RATE_LIMIT %d, expected at least 0", mailingRateLimit)
+	}
+	config.MailingRateLimit = mailingRateLimit
+
+	mailingRateWindow, err := getEnvDuration("MAILING_RATE_WINDOW", time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	if mailingRateWindow <= 0 {
+		return nil, fmt.Errorf("Invalid MAILING_RATE_WINDOW %v, expected a positive duration", mailingRateWindow)
+	}
+	config.MailingRateWindow = mailingRateWindow
+
+	config.TempLogin = os.Getenv("TEMP_LOGIN") == "1"
+	if zids := getEnv("ADMIN_ZIDS", ""); zids != "" {
+		for _, zid := range strings.Split(zids, ",") {
+			config.AdminZIDs = append(config.AdminZIDs, strings.TrimSpace(zid))
+		}
+	}
+
+	jwtSecret := os.Getenv("JWT_SECRET")
+	if jwtSecret == "" {
+		// Decided at runtime, as a build that forgot to turn off DEVELOPMENT must still fail
+		if os.Getenv("ALLOW_DEV_JWT_SECRET") != "1" {
+			return nil, fmt.Errorf("Missing required environment variable: JWT_SECRET")
+		}
+		log.Println("Warning: JWT_SECRET is unset, signing tokens with the public development secret")
+		jwtSecret = devJWTSecret
+	}
+	config.JWTSecret = []byte(jwtSecret)
+
+	return config, nil
+}
+
+// TLSEnabled - whether the server is configured to serve HTTPS
+func (c *Config) TLSEnabled() bool {
+	return c.TLSCertFile != "" || len(c.AutoTLSHosts) > 0
+}
+
+// getEnv - returns the value of an environment variable or a fallback if it is unset
+func getEnv(key string, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok && value != "" {
+		return value
+	}
+	return fallback
+}
+
+// getEnvDuration - parses a duration (e.g. "5s") from an environment variable
+func getEnvDuration(key string, fallback time.Duration) (time.Duration, error) {
+	value := getEnv(key, "")
+	if value == "" {
+		return fallback, nil
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid duration for %s: %v", key, err)
+	}
+	return duration, nil
+}
+
+// getEnvInt - parses an integer from an environment variable
+func getEnvInt(key string, fallback int) (int, error) {
+	value := getEnv(key, "")
+	if value == "" {
+		return fallback, nil
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid integer for %s: %v", key, err)
+	}
+	return number, nil
+}
+
+// getEnvFloat - parses a decimal number from an environment variable
+func getEnvFloat(key string, fallback float64) (float64, error) {
+	value := getEnv(key, "")
+	if value == "" {
+		return fallback, nil
+	}
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid number for %s: %v", key, err)
+	}
+	return number, nil
+}
+
+// contains - reports whether a string is one of the given values
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
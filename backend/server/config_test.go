@@ -0,0 +1,54 @@
+package utility
+
+import (
+	"os"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+	setEnv := func(key string, value string) {
+		old, ok := os.LookupEnv(key)
+		if value == "" {
+			os.Unsetenv(key)
+		} else {
+			os.Setenv(key, value)
+		}
+		t.Cleanup(func() {
+			if ok {
+				os.Setenv(key, old)
+			} else {
+				os.Unsetenv(key)
+			}
+		})
+	}
+
+	t.Run("JWT secret is required", func(t *testing.T) {
+		setEnv("JWT_SECRET", "")
+		setEnv("ALLOW_DEV_JWT_SECRET", "")
+		if _, err := LoadConfig(); err == nil {
+			t.Errorf("Expected a missing JWT_SECRET to be rejected")
+		}
+	})
+
+	t.Run("Development secret must be opted in to", func(t *testing.T) {
+		setEnv("JWT_SECRET", "")
+		setEnv("ALLOW_DEV_JWT_SECRET", "1")
+		config, err := LoadConfig()
+		if err != nil {
+			t.Errorf("Could not load config: %v", err)
+			return
+		}
+		AssertResponseBody(t, string(config.JWTSecret), devJWTSecret)
+	})
+
+	t.Run("Configured secret is used", func(t *testing.T) {
+		setEnv("JWT_SECRET", "s3cret")
+		setEnv("ALLOW_DEV_JWT_SECRET", "1")
+		config, err := LoadConfig()
+		if err != nil {
+			t.Errorf("Could not load config: %v", err)
+			return
+		}
+		AssertResponseBody(t, string(config.JWTSecret), "s3cret")
+	})
+}
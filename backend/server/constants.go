@@ -16,12 +16,29 @@ const SOCIAL_URL = "api/v1/social"
 const EVENTS_URL = "api/v1/events"
 const FAQ_URL = "api/v1/faq"
 const RESOURCES_URL = "api/v1/resources"
+const AUDIT_URL = "api/v1/audit"
+const USERS_URL = "api/v1/users"
+const HEALTH_URL = "healthz"
+const LIVENESS_URL = "livez"
+const READINESS_URL = "readyz"
+
+// Probe endpoints polled by orchestrators and load balancers, never rate limited
+var PROBE_URLS = []string{HEALTH_URL, LIVENESS_URL, READINESS_URL}
+
+const VERSION_URL = "version"
+const LOGIN_URL = "login"
+const LOGOUT_URL = "logout"
+const REFRESH_URL = "token/refresh"
+const ME_URL = "me"
+const LOGO_URL = "uploads/logos"
+
+// Default pagination of list endpoints, see PAGE_LIMIT_DEFAULT and PAGE_LIMIT_MAX
+const DEFAULT_PAGE_LIMIT = 50
+const MAX_PAGE_LIMIT = 100
 
 // JWT used for testing
 var AUTH_TOKEN = "Bearer " + os.Getenv("TESTING_JWT")
 
-var JWT_SECRET = []byte(os.Getenv("JWT_SECRET"))
-
 // Mailing
 const INFO_EMAIL = "info@csesoc.org.au"
 const DEV_INFO_EMAIL = "projects.website+info@csesoc.org.au"
@@ -0,0 +1,118 @@
+/*
+  Errors
+  --
+  This file defines the errors handlers return instead of writing error responses
+  themselves. Every error is sent to clients in the same envelope:
+    {"error": {"code": "not_found", "message": "No such sponsor"}}
+  where the code is derived from the status, so clients can branch on the code
+  and show the message.
+*/
+
+package utility
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// AppError - an error response with its status, code and message
+type AppError struct {
+	Status  int    `json:"-"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error - returns the message of the error
+func (e *AppError) Error() string {
+	return e.Message
+}
+
+// NewAppError - creates an error responding with the given status
+func NewAppError(status int, message string) *AppError {
+	return &AppError{
+		Status:  status,
+		Code:    errorCode(status),
+		Message: message,
+	}
+}
+
+// BadRequest - the request was malformed or failed validation (400)
+func BadRequest(message string) *AppError {
+	return NewAppError(http.StatusBadRequest, message)
+}
+
+// Unauthorized - the request is missing valid credentials (401)
+func Unauthorized(message string) *AppError {
+	return NewAppError(http.StatusUnauthorized, message)
+}
+
+// Forbidden - the credentials are valid but lack permission (403)
+func Forbidden(message string) *AppError {
+	return NewAppError(http.StatusForbidden, message)
+}
+
+// NotFound - the requested resource does not exist (404)
+func NotFound(message string) *AppError {
+	return NewAppError(http.StatusNotFound, message)
+}
+
+// Conflict - the resource already exists (409)
+func Conflict(message string) *AppError {
+	return NewAppError(http.StatusConflict, message)
+}
+
+// TooManyRequests - the client has been rate limited (429)
+func TooManyRequests(message string) *AppError {
+	return NewAppError(http.StatusTooManyRequests, message)
+}
+
+// Internal - the server failed to handle the request (500)
+func Internal(message string) *AppError {
+	return NewAppError(http.StatusInternalServerError, message)
+}
+
+// Unavailable - a service the server depends on could not be reached (503)
+func Unavailable(message string) *AppError {
+	return NewAppError(http.StatusServiceUnavailable, message)
+}
+
+// HTTPErrorHandler - writes every error returned by a handler or middleware in the error envelope.
+// Errors that are not AppErrors or echo.HTTPErrors are logged and hidden behind a generic 500.
+func HTTPErrorHandler(err error, c echo.Context) {
+	if c.Response().Committed {
+		return
+	}
+
+	appErr, ok := err.(*AppError)
+	if !ok {
+		if httpErr, isHTTPErr := err.(*echo.HTTPError); isHTTPErr {
+			appErr = NewAppError(httpErr.Code, fmt.Sprint(httpErr.Message))
+		} else {
+			LogError(c, err)
+			appErr = Internal("internal server error")
+		}
+	}
+
+	if c.Request().Method == http.MethodHead {
+		err = c.NoContent(appErr.Status)
+	} else {
+		err = c.JSON(appErr.Status, H{
+			"error": appErr,
+		})
+	}
+	if err != nil {
+		LogError(c, err)
+	}
+}
+
+// errorCode - returns the code of a status, e.g. 404 is "not_found"
+func errorCode(status int) string {
+	text := http.StatusText(status)
+	if text == "" {
+		return "unknown"
+	}
+	return strings.ReplaceAll(strings.ToLower(text), " ", "_")
+}
@@ -0,0 +1,45 @@
+package utility
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestHTTPErrorHandler(t *testing.T) {
+	respond := func(err error) (int, map[string]*AppError) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		rec := httptest.NewRecorder()
+		HTTPErrorHandler(err, echo.New().NewContext(req, rec))
+
+		var body map[string]*AppError
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+			t.Errorf("Error parsing JSON response: %v", err)
+		}
+		return rec.Code, body
+	}
+
+	t.Run("Application errors keep their status and message", func(t *testing.T) {
+		status, body := respond(NotFound("No such sponsor"))
+		AssertStatus(t, status, http.StatusNotFound)
+		AssertResponseBody(t, body["error"].Code, "not_found")
+		AssertResponseBody(t, body["error"].Message, "No such sponsor")
+	})
+
+	t.Run("Echo errors are wrapped in the envelope", func(t *testing.T) {
+		status, body := respond(echo.ErrStatusRequestEntityTooLarge)
+		AssertStatus(t, status, http.StatusRequestEntityTooLarge)
+		AssertResponseBody(t, body["error"].Code, "request_entity_too_large")
+	})
+
+	t.Run("Other errors are hidden behind a 500", func(t *testing.T) {
+		status, body := respond(errors.New("connection refused"))
+		AssertStatus(t, status, http.StatusInternalServerError)
+		AssertResponseBody(t, body["error"].Code, "internal_server_error")
+		AssertResponseBody(t, body["error"].Message, "internal server error")
+	})
+}
@@ -98,13 +98,11 @@ type (
 // @Tags events
 // @Success 200 {array} Event
 // @Failure 500 {string} error "Unable to retrieve events from file"
-// @Router /events [get]
+// @Router /api/v1/events [get]
 func HandleGet(c echo.Context) error {
 	fp, err := filepath.Abs("static/events.json")
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, H{
-			"error": "Unable to retrieve events",
-		})
+		return Internal("Unable to retrieve events")
 	}
 	return c.File(fp)
 }
@@ -38,14 +38,14 @@ var faqColl *mongo.Collection
 func Setup(client *mongo.Client) {
 	faqColl = client.Database("csesoc").Collection("faqs")
 
-	// Creating unique index for sponsor name
-	opt := options.Index()
-	opt.SetUnique(true)
-	index := mongo.IndexModel{
-		Keys:    bson.M{"question": 1},
-		Options: opt,
+	// Creating unique index for faq question
+	indexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "question", Value: 1}},
+			Options: options.Index().SetName("question_1").SetUnique(true),
+		},
 	}
-	if _, err := faqColl.Indexes().CreateOne(context.Background(), index); err != nil {
+	if err := EnsureIndexes(faqColl, indexes); err != nil {
 		log.Fatal("Could not create index: ", err)
 	}
 
@@ -76,22 +76,20 @@ func Setup(client *mongo.Client) {
 // @Success 200 {array} Faq
 // @Failure 500 {string} error "Missing questions and/or answer fields"
 // @Failure 503 {string} error "Unable to retrieve FAQs"
-// @Router /faq [get]
+// @Router /api/v1/faq [get]
 func HandleGet(c echo.Context) error {
-	faqs, err := retrieveFaqs()
+	ctx, cancel := DBContext(c)
+	defer cancel()
+	faqs, err := retrieveFaqs(ctx)
 
 	if err != nil {
-		return c.JSON(http.StatusServiceUnavailable, H{
-			"error": "Unable to retrieve FAQs from database",
-		})
+		return Unavailable("Unable to retrieve FAQs from database")
 	}
 
 	// Validate structs
 	for _, faq := range faqs {
 		if err := c.Validate(faq); err != nil {
-			return c.JSON(http.StatusInternalServerError, H{
-				"error": "Missing questions and/or answer fields",
-			})
+			return Internal("Missing questions and/or answer fields")
 		}
 	}
 
@@ -102,13 +100,14 @@ func HandleGet(c echo.Context) error {
 // HELPERS
 //////////
 
-func retrieveFaqs() ([]*Faq, error) {
+func retrieveFaqs(ctx context.Context) ([]*Faq, error) {
 	var results []*Faq
 
-	curr, err := faqColl.Find(context.TODO(), bson.M{})
+	curr, err := faqColl.Find(ctx, bson.M{})
 	// decode result into faq array
 	if err == nil {
-		for curr.Next(context.TODO()) {
+		defer curr.Close(ctx)
+		for curr.Next(ctx) {
 			var elem Faq
 			curr.Decode(&elem)
 			results = append(results, &elem)
@@ -0,0 +1,147 @@
+/*
+  Gzip
+  --
+  This file provides middleware compressing responses for clients that accept gzip.
+  Echo's Gzip middleware compresses everything, so responses are buffered until they
+  reach a minimum length and smaller ones are sent as is, where compression would cost
+  more than it saves. Streamed responses are compressed as soon as they are flushed.
+*/
+
+package utility
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// gzipResponseWriter - holds back the response until it is known whether to compress it
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	minLength int
+	status    int
+	buf       []byte
+	gz        *gzip.Writer
+	started   bool
+}
+
+// Gzip - compresses responses of at least minLength bytes for clients that accept gzip
+func Gzip(minLength int) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) (err error) {
+			res := c.Response()
+			res.Header().Add(echo.HeaderVary, echo.HeaderAcceptEncoding)
+			if !strings.Contains(c.Request().Header.Get(echo.HeaderAcceptEncoding), "gzip") {
+				return next(c)
+			}
+
+			w := &gzipResponseWriter{
+				ResponseWriter: res.Writer,
+				minLength:      minLength,
+				status:         http.StatusOK,
+			}
+			res.Writer = w
+			defer func() {
+				if closeErr := w.close(res.Committed); closeErr != nil && err == nil {
+					err = closeErr
+				}
+				res.Writer = w.ResponseWriter
+			}()
+
+			// Let the error handler write the response while it can still be compressed
+			if err = next(c); err != nil {
+				c.Error(err)
+			}
+			return err
+		}
+	}
+}
+
+// WriteHeader - records the status, which is sent once the body is large enough to decide
+func (w *gzipResponseWriter) WriteHeader(code int) {
+	w.status = code
+}
+
+// Write - buffers the body until it reaches the minimum length
+func (w *gzipResponseWriter) Write(b []byte) (int, error) {
+	if w.started {
+		if w.gz != nil {
+			return w.gz.Write(b)
+		}
+		return w.ResponseWriter.Write(b)
+	}
+
+	w.buf = append(w.buf, b...)
+	if len(w.buf) >= w.minLength {
+		if err := w.start(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// Flush - sends what has been written so far, compressing it as the response is being streamed
+func (w *gzipResponseWriter) Flush() {
+	if !w.started {
+		w.start(true)
+	}
+	if w.gz != nil {
+		w.gz.Flush()
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// start - sends the headers and buffered body, compressing from here on if asked to
+func (w *gzipResponseWriter) start(compress bool) error {
+	w.started = true
+	header := w.Header()
+
+	// The body would be sniffed after compression, so sniff it now
+	if header.Get(echo.HeaderContentType) == "" && len(w.buf) > 0 {
+		header.Set(echo.HeaderContentType, http.DetectContentType(w.buf))
+	}
+	if compress && canCompress(w.status) && header.Get(echo.HeaderContentEncoding) == "" {
+		header.Set(echo.HeaderContentEncoding, "gzip")
+		header.Del(echo.HeaderContentLength)
+		w.gz = gzip.NewWriter(w.ResponseWriter)
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	if w.gz != nil {
+		_, err := w.gz.Write(buf)
+		return err
+	}
+	_, err := w.ResponseWriter.Write(buf)
+	return err
+}
+
+// close - sends a response that never reached the minimum length, or finishes the compressed one
+func (w *gzipResponseWriter) close(committed bool) error {
+	if !w.started {
+		// Nothing was written, so leave the response to echo
+		if !committed {
+			return nil
+		}
+		return w.start(false)
+	}
+	if w.gz != nil {
+		return w.gz.Close()
+	}
+	return nil
+}
+
+// canCompress - partial and empty responses must be sent as is
+func canCompress(status int) bool {
+	return status != http.StatusPartialContent &&
+		status != http.StatusNoContent &&
+		status != http.StatusNotModified
+}
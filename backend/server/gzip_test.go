@@ -0,0 +1,67 @@
+package utility
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestGzip(t *testing.T) {
+	serve := func(handler echo.HandlerFunc, acceptEncoding string) *httptest.ResponseRecorder {
+		e := echo.New()
+		e.Use(Gzip(16))
+		e.GET("/", handler)
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(echo.HeaderAcceptEncoding, acceptEncoding)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+	long := strings.Repeat("sponsor ", 16)
+
+	t.Run("Short responses are not compressed", func(t *testing.T) {
+		rec := serve(func(c echo.Context) error {
+			return c.String(http.StatusOK, "ok")
+		}, "gzip")
+		AssertStatus(t, rec.Code, http.StatusOK)
+		AssertResponseBody(t, rec.Header().Get(echo.HeaderContentEncoding), "")
+		AssertResponseBody(t, rec.Body.String(), "ok")
+	})
+
+	t.Run("Long responses are compressed", func(t *testing.T) {
+		rec := serve(func(c echo.Context) error {
+			return c.String(http.StatusCreated, long)
+		}, "gzip, deflate")
+		AssertStatus(t, rec.Code, http.StatusCreated)
+		AssertResponseBody(t, rec.Header().Get(echo.HeaderContentEncoding), "gzip")
+
+		reader, err := gzip.NewReader(rec.Body)
+		if err != nil {
+			t.Errorf("Could not read gzip body: %v", err)
+			return
+		}
+		body, _ := ioutil.ReadAll(reader)
+		AssertResponseBody(t, string(body), long)
+	})
+
+	t.Run("Clients that don't accept gzip get plain responses", func(t *testing.T) {
+		rec := serve(func(c echo.Context) error {
+			return c.String(http.StatusOK, long)
+		}, "")
+		AssertResponseBody(t, rec.Header().Get(echo.HeaderContentEncoding), "")
+		AssertResponseBody(t, rec.Body.String(), long)
+	})
+
+	t.Run("Errors are sent through the error handler", func(t *testing.T) {
+		rec := serve(func(c echo.Context) error {
+			return echo.ErrNotFound
+		}, "gzip")
+		AssertStatus(t, rec.Code, http.StatusNotFound)
+	})
+}
@@ -0,0 +1,125 @@
+/*
+  Health
+  --
+  This module reports whether the server is able to handle requests.
+  It is polled by the load balancer and Kubernetes to decide where to route traffic:
+  /livez only checks the process is up, while /readyz also needs MongoDB to answer
+  and starts failing as soon as the server begins shutting down, so traffic drains
+  away before connections are refused. /version reports which build is running.
+*/
+
+package health
+
+import (
+	"context"
+	"net/http"
+	"runtime"
+	"sync/atomic"
+	"time"
+
+	. "csesoc.unsw.edu.au/m/v2/server"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// pingTimeout - how long to wait for MongoDB before reporting it as unavailable
+const pingTimeout = 2 * time.Second
+
+// Commit and BuildTime - identify the build, set at build time with -ldflags "-X", see the Dockerfile
+var (
+	Commit    = "dev"
+	BuildTime = "dev"
+)
+
+var mongoClient *mongo.Client
+
+// draining - set to 1 once the server is shutting down
+var draining int32
+
+////////
+// SETUP
+////////
+
+// Setup - setup the client whose connection is checked
+func Setup(client *mongo.Client) {
+	mongoClient = client
+}
+
+///////////
+// HANDLERS
+///////////
+
+// HandleHealthz godoc
+// @Summary Check MongoDB answers a ping
+// @Tags health
+// @Success 200 {object} utility.H "status"
+// @Failure 503 {string} error "Unable to reach database"
+// @Router /healthz [get]
+func HandleHealthz(c echo.Context) error {
+	if err := pingDB(c); err != nil {
+		return Unavailable("Unable to reach database")
+	}
+	return c.JSON(http.StatusOK, H{
+		"status": "ok",
+	})
+}
+
+// HandleLivez godoc
+// @Summary Check the process is able to serve requests
+// @Tags health
+// @Success 200 {object} utility.H "status"
+// @Router /livez [get]
+func HandleLivez(c echo.Context) error {
+	return c.JSON(http.StatusOK, H{
+		"status": "ok",
+	})
+}
+
+// HandleReadyz godoc
+// @Summary Check the server should be sent traffic
+// @Description Succeeds when MongoDB answers a ping and the server is not shutting down.
+// @Tags health
+// @Success 200 {object} utility.H "status"
+// @Failure 503 {string} error "Shutting down"
+// @Router /readyz [get]
+func HandleReadyz(c echo.Context) error {
+	if atomic.LoadInt32(&draining) == 1 {
+		return Unavailable("Shutting down")
+	}
+	if err := pingDB(c); err != nil {
+		return Unavailable("Unable to reach database")
+	}
+	return c.JSON(http.StatusOK, H{
+		"status": "ok",
+	})
+}
+
+// HandleVersion godoc
+// @Summary Get the commit, build time and Go version of the running server
+// @Tags health
+// @Success 200 {object} utility.H "commit, buildTime and goVersion"
+// @Router /version [get]
+func HandleVersion(c echo.Context) error {
+	return c.JSON(http.StatusOK, H{
+		"commit":    Commit,
+		"buildTime": BuildTime,
+		"goVersion": runtime.Version(),
+	})
+}
+
+//////////
+// HELPERS
+//////////
+
+// StartDraining - fails readiness checks from now on so traffic is routed elsewhere before shutdown
+func StartDraining() {
+	atomic.StoreInt32(&draining, 1)
+}
+
+// pingDB - checks MongoDB answers within pingTimeout
+func pingDB(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Request().Context(), pingTimeout)
+	defer cancel()
+	return mongoClient.Ping(ctx, nil)
+}
@@ -0,0 +1,54 @@
+package health
+
+import (
+	"net/http"
+	"testing"
+
+	. "csesoc.unsw.edu.au/m/v2/server"
+)
+
+func TestHealth(t *testing.T) {
+	t.Run("Database reachable test", func(t *testing.T) {
+		resp, err := http.Get(BASE_URL + HEALTH_URL)
+		if err != nil {
+			t.Errorf("Could not perform GET request: %v", err)
+			return
+		}
+		defer resp.Body.Close()
+
+		AssertStatus(t, resp.StatusCode, http.StatusOK)
+	})
+
+	t.Run("Liveness test", func(t *testing.T) {
+		resp, err := http.Get(BASE_URL + LIVENESS_URL)
+		if err != nil {
+			t.Errorf("Could not perform GET request: %v", err)
+			return
+		}
+		defer resp.Body.Close()
+
+		AssertStatus(t, resp.StatusCode, http.StatusOK)
+	})
+
+	t.Run("Readiness test", func(t *testing.T) {
+		resp, err := http.Get(BASE_URL + READINESS_URL)
+		if err != nil {
+			t.Errorf("Could not perform GET request: %v", err)
+			return
+		}
+		defer resp.Body.Close()
+
+		AssertStatus(t, resp.StatusCode, http.StatusOK)
+	})
+
+	t.Run("Version test", func(t *testing.T) {
+		resp, err := http.Get(BASE_URL + VERSION_URL)
+		if err != nil {
+			t.Errorf("Could not perform GET request: %v", err)
+			return
+		}
+		defer resp.Body.Close()
+
+		AssertStatus(t, resp.StatusCode, http.StatusOK)
+	})
+}
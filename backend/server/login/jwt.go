@@ -7,61 +7,239 @@
 package login
 
 import (
-	"net/http"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"sync"
 	"time"
 
 	. "csesoc.unsw.edu.au/m/v2/server"
 
 	"github.com/dgrijalva/jwt-go"
 	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-var tempUsers = map[string]string{
-	"z5123456": "t3stP@ssw0rd",
+const (
+	// accessTokenLifetime - how long an access token is accepted for
+	accessTokenLifetime = 15 * time.Minute
+	// refreshTokenLifetime - how long a refresh token can be exchanged for access tokens
+	refreshTokenLifetime = 7 * 24 * time.Hour
+)
+
+const (
+	// claimsContextKey - key under which the claims of a validated token are stored in the echo context
+	claimsContextKey = "user"
+	// bearerScheme - prefix of the Authorization header carrying a token
+	bearerScheme = "Bearer "
+	// maxTokenLength - longest token parsed, ours are a few hundred bytes
+	maxTokenLength = 2048
+	// base64URLAlphabet - characters a JWT segment may contain, as they are unpadded base64url
+	base64URLAlphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-_"
+	// tokenVersionTTL - how long a token version read from the database is trusted for
+	tokenVersionTTL = 30 * time.Second
+)
+
+// cachedTokenVersion - a user's token version and when it has to be read again
+type cachedTokenVersion struct {
+	version int
+	expires time.Time
 }
 
-// createJwtToken creates a new JWT and returns it as a string.
-func createJwtToken(zID string, admin bool) (string, time.Time, error) {
-	unsignedToken := jwt.New(jwt.SigningMethodHS256)
-	claims := unsignedToken.Claims.(jwt.MapClaims)
-	expTime := time.Now().Add(time.Hour * 72)
-	claims["zID"] = zID
-	claims["admin"] = true
-	claims["exp"] = expTime.Unix()
+// tokenVersions - token versions recently read by RequireAuth, keyed by hashed zID
+var tokenVersions = struct {
+	sync.Mutex
+	entries map[string]cachedTokenVersion
+}{entries: map[string]cachedTokenVersion{}}
 
-	token, err := unsignedToken.SignedString(JWT_SECRET)
-	if err != nil {
-		return "", time.Now(), err
+// signAccessToken - creates a short-lived JWT identifying a user, valid until their token version changes
+func signAccessToken(hashedZID string, firstName string, role string, version int) (string, error) {
+	claims := &Claims{
+		HashedZID: hashedZID,
+		FirstName: firstName,
+		Role:      role,
+		Version:   version,
+		StandardClaims: jwt.StandardClaims{
+			ExpiresAt: time.Now().Add(accessTokenLifetime).Unix(),
+		},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(jwtSecret)
+}
+
+// hashZID - returns the hex encoded sha256 of a zID, used to identify users without storing their zID
+func hashZID(zid string) string {
+	sum := sha256.Sum256([]byte(zid))
+	return hex.EncodeToString(sum[:])
+}
+
+// newRefreshToken - returns a random refresh token and the hash to store in its place
+func newRefreshToken() (string, string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	token := hex.EncodeToString(buf)
+	return token, hashRefreshToken(token), nil
+}
+
+// hashRefreshToken - refresh tokens are only stored hashed so a database leak can't be replayed
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// RequireAuth - middleware that rejects requests without a valid bearer token.
+// Malformed headers are turned away before any signature is checked, and the token
+// is only parsed once, with its claims stored in the echo context, see GetClaims.
+// Tokens of users who have since logged out are rejected by their version. Versions are
+// cached for tokenVersionTTL rather than read on every request, so logging out revokes
+// tokens at once on the server handling it, but other replicas may accept them for up
+// to tokenVersionTTL longer, well within the accessTokenLifetime they'd be valid for anyway.
+func RequireAuth() echo.MiddlewareFunc {
+	parser := &jwt.Parser{ValidMethods: []string{jwt.SigningMethodHS256.Name}}
+	keyFunc := func(token *jwt.Token) (interface{}, error) {
+		return jwtSecret, nil
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			token, ok := bearerToken(c.Request().Header.Get(echo.HeaderAuthorization))
+			if !ok {
+				return Unauthorized("Invalid or missing token")
+			}
+
+			claims := &Claims{}
+			if _, err := parser.ParseWithClaims(token, claims, keyFunc); err != nil {
+				// Only a correctly signed token that has just run out is worth refreshing
+				if validationErr, ok := err.(*jwt.ValidationError); ok && validationErr.Errors == jwt.ValidationErrorExpired {
+					return Unauthorized("Token expired")
+				}
+				return Unauthorized("Invalid or missing token")
+			}
+			// jwt-go accepts tokens without an expiry, which we never issue
+			if claims.ExpiresAt == 0 {
+				return Unauthorized("Invalid or missing token")
+			}
+
+			// Tokens issued before the user logged out carry an old version
+			ctx, cancel := DBContext(c)
+			version, err := tokenVersion(ctx, claims.HashedZID)
+			cancel()
+			if err == mongo.ErrNoDocuments || (err == nil && version != claims.Version) {
+				return Unauthorized("Invalid or missing token")
+			}
+			if err != nil {
+				return Internal("Unable to check token")
+			}
+
+			c.Set(claimsContextKey, claims)
+			return next(c)
+		}
 	}
-	return token, expTime, nil
 }
 
-// TempLogin allows auth functionality to be tested before LDAP is implemented
-func TempLogin(c echo.Context) error {
-	userzID := c.QueryParam("zID")
-	password := c.QueryParam("password")
-	expectedPass, ok := tempUsers[userzID]
-	if !ok || password != expectedPass {
-		return c.String(http.StatusUnauthorized, "Your username or password was incorrect.")
+// OptionalAuth - middleware that authenticates callers who send a bearer token, as RequireAuth
+// does, and lets anonymous callers through without claims
+func OptionalAuth() echo.MiddlewareFunc {
+	requireAuth := RequireAuth()
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		authenticated := requireAuth(next)
+		return func(c echo.Context) error {
+			if c.Request().Header.Get(echo.HeaderAuthorization) == "" {
+				return next(c)
+			}
+			return authenticated(c)
+		}
 	}
+}
 
-	// Create a new token.
-	token, expTime, err := createJwtToken(userzID, true)
+// tokenVersion - returns the token version of a user, reading it from the database
+// at most once every tokenVersionTTL
+func tokenVersion(ctx context.Context, hashedZID string) (int, error) {
+	now := time.Now()
+	tokenVersions.Lock()
+	cached, ok := tokenVersions.entries[hashedZID]
+	tokenVersions.Unlock()
+	if ok && now.Before(cached.expires) {
+		return cached.version, nil
+	}
+
+	version, err := currentTokenVersion(ctx, hashedZID)
 	if err != nil {
-		return c.String(http.StatusInternalServerError, "500 Internal Error")
+		return 0, err
+	}
+
+	tokenVersions.Lock()
+	defer tokenVersions.Unlock()
+	// Drop users who haven't made a request lately, so the cache stays as small as the active users
+	for key, entry := range tokenVersions.entries {
+		if !now.Before(entry.expires) {
+			delete(tokenVersions.entries, key)
+		}
 	}
+	tokenVersions.entries[hashedZID] = cachedTokenVersion{version: version, expires: now.Add(tokenVersionTTL)}
+	return version, nil
+}
 
-	// Create a cookie to store the JWT.
-	tokenCookie := new(http.Cookie)
-	tokenCookie.Name = "activeToken"
-	tokenCookie.Value = token
-	tokenCookie.Expires = expTime
-	tokenCookie.HttpOnly = true
-	// c.SetCookie(tokenCookie)
+// forgetTokenVersion - makes the next request of a user read their token version again
+func forgetTokenVersion(hashedZID string) {
+	tokenVersions.Lock()
+	defer tokenVersions.Unlock()
+	delete(tokenVersions.entries, hashedZID)
+}
 
-	return c.JSON(http.StatusOK, map[string]string{
-		"message": "Success!!",
-		"token":   token,
-	})
+// currentTokenVersion - returns the token version stored against a user, replaced in tests
+var currentTokenVersion = func(ctx context.Context, hashedZID string) (int, error) {
+	var user User
+	filter := bson.D{{Key: "userID", Value: hashedZID}}
+	opts := options.FindOne().SetProjection(bson.D{{Key: "tokenVersion", Value: 1}})
+	err := userColl.FindOne(ctx, filter, opts).Decode(&user)
+	return user.TokenVersion, err
+}
 
+// bearerToken - returns the token of a "Bearer <token>" header if it is shaped like a JWT,
+// i.e. three non-empty base64url segments and no longer than maxTokenLength
+func bearerToken(header string) (string, bool) {
+	if len(header) <= len(bearerScheme) || !strings.EqualFold(header[:len(bearerScheme)], bearerScheme) {
+		return "", false
+	}
+	token := header[len(bearerScheme):]
+	if len(token) > maxTokenLength {
+		return "", false
+	}
+
+	segments := strings.Split(token, ".")
+	if len(segments) != 3 {
+		return "", false
+	}
+	for _, segment := range segments {
+		if segment == "" || strings.TrimLeft(segment, base64URLAlphabet) != "" {
+			return "", false
+		}
+	}
+	return token, true
+}
+
+// GetClaims - returns the claims of the caller authenticated by RequireAuth
+func GetClaims(c echo.Context) (*Claims, bool) {
+	claims, ok := c.Get(claimsContextKey).(*Claims)
+	return claims, ok
+}
+
+// RequireRole - middleware that rejects callers whose role does not match.
+// Must be chained after RequireAuth.
+func RequireRole(role string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			claims, ok := GetClaims(c)
+			if !ok || claims.Role != role {
+				return Forbidden("Insufficient permissions")
+			}
+			return next(c)
+		}
+	}
 }
@@ -0,0 +1,102 @@
+/*
+  LDAP
+  --
+  This file checks credentials against UNSW's LDAP server. Login depends on the
+  Authenticator interface rather than LDAP directly so it can be tested without
+  reaching Active Directory.
+*/
+
+package login
+
+import (
+	"fmt"
+
+	"gopkg.in/ldap.v2"
+)
+
+// Authenticator - checks a zID and password, returning the user's first name
+type Authenticator interface {
+	Authenticate(zid string, password string) (string, error)
+}
+
+// LDAPAuthenticator - authenticates against an LDAP server such as UNSW's Active Directory
+type LDAPAuthenticator struct {
+	Host string
+}
+
+// tempAuthenticator - accepts the test accounts in tempUsers, used instead of LDAP when
+// TEMP_LOGIN=1 so login can be exercised without UNSW credentials
+type tempAuthenticator struct{}
+
+// tempUsers - zIDs and passwords accepted by tempAuthenticator. They are public, so
+// they only get the role stored against them, see ADMIN_ZIDS.
+var tempUsers = map[string]string{
+	"z5123456": "t3stP@ssw0rd",
+	"z5654321": "t3stP@ssw0rd2",
+}
+
+const (
+	// ldapDomain - domain of the UPNs UNSW accounts bind with
+	ldapDomain = "ad.unsw.edu.au"
+	// ldapBaseDN - subtree of Identity Manager holding people
+	ldapBaseDN = "OU=IDM_People,OU=IDM,DC=ad,DC=unsw,DC=edu,DC=au"
+	// firstNameAttribute - LDAP attribute holding a user's first name
+	firstNameAttribute = "givenName"
+)
+
+// Authenticate - binds as the user to check their password, then looks up their first name
+func (a *LDAPAuthenticator) Authenticate(zid string, password string) (string, error) {
+	// An empty password would be accepted as an anonymous bind
+	if zid == "" || password == "" {
+		return "", errInvalidCredentials
+	}
+
+	// Connect to UNSW LDAP server
+	l, err := ldap.Dial("tcp", a.Host)
+	if err != nil {
+		return "", errLDAPUnavailable
+	}
+	defer l.Close()
+
+	// Attempt to sign in using credentials
+	if err := l.Bind(bindUsername(zid), password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return "", errInvalidCredentials
+		}
+		return "", errLDAPUnavailable
+	}
+
+	// Retrieve first name from Identity Manager
+	searchRequest := ldap.NewSearchRequest(
+		ldapBaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
+		searchFilter(zid), []string{firstNameAttribute}, nil,
+	)
+
+	searchResult, err := l.Search(searchRequest)
+	if err != nil {
+		return "", errLDAPUnavailable
+	}
+	if len(searchResult.Entries) == 0 {
+		return "", fmt.Errorf("No directory entry for %s", zid)
+	}
+	return searchResult.Entries[0].GetAttributeValue(firstNameAttribute), nil
+}
+
+// Authenticate - checks the zID and password against tempUsers
+func (a *tempAuthenticator) Authenticate(zid string, password string) (string, error) {
+	expected, ok := tempUsers[zid]
+	if !ok || password != expected {
+		return "", errInvalidCredentials
+	}
+	return "Test", nil
+}
+
+// bindUsername - returns the user principal name to bind to LDAP as, e.g. z1234567@ad.unsw.edu.au
+func bindUsername(zid string) string {
+	return zid + "@" + ldapDomain
+}
+
+// searchFilter - returns the LDAP filter matching the account of a zID (its common name)
+func searchFilter(zid string) string {
+	return "(cn=" + ldap.EscapeFilter(zid) + ")"
+}
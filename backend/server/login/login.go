@@ -2,149 +2,376 @@
   Login
   --
   This module constitutes the groundwork for the authentication system
-  using UNSW's LDAP server.
+  using UNSW's LDAP server, see ldap.go.
 */
 
 package login
 
 import (
 	"context"
-	"crypto/sha256"
+	"errors"
 	"log"
+	"net/http"
 	"time"
 
+	. "csesoc.unsw.edu.au/m/v2/server"
+
 	"github.com/dgrijalva/jwt-go"
+	"github.com/labstack/echo/v4"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
-	"gopkg.in/ldap.v2"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type (
 	// User - struct to contain user data
 	User struct {
-		UserID    string //sha256 the zid
-		UserToken string
-		Role      string
+		UserID        string `bson:"userID" json:"userID"` // hex encoded sha256 of the zid
+		UserToken     string `bson:"userToken" json:"-"`
+		Role          string `bson:"role" json:"role"`
+		FirstName     string `bson:"firstName" json:"firstName"`
+		RefreshToken  string `bson:"refreshToken" json:"-"` // sha256 of the refresh token
+		RefreshExpiry int64  `bson:"refreshExpiry" json:"-"`
+		TokenVersion  int    `bson:"tokenVersion" json:"-"` // bumped on logout to revoke access tokens
 	}
 
 	// Claims - struct to store jwt data
 	Claims struct {
-		HashedZID   [32]byte
-		FirstName   string
-		Permissions string
+		HashedZID string `json:"hashedZID"` // hex encoded sha256 of the zid
+		FirstName string `json:"firstName"`
+		Role      string `json:"role"`
+		Version   int    `json:"ver"` // token version of the user when the token was issued
 		jwt.StandardClaims
 	}
 )
 
-var jwtKey = []byte("secret_text")
+// Roles that can be assigned to a user
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
 
-// Auth - to login
-func Auth(collection *mongo.Collection, zid string, password string, permissions string) string {
-	// Connect to UNSW LDAP server
-	l, err := ldap.Dial("tcp", "ad.unsw.edu.au")
-	if err != nil {
-		log.Fatal(err)
+var (
+	// errInvalidCredentials - the zID and password were rejected
+	errInvalidCredentials = errors.New("Invalid credentials")
+	// errLDAPUnavailable - the LDAP server could not be reached or failed to answer
+	errLDAPUnavailable = errors.New("Authentication server unavailable")
+)
+
+var userColl *mongo.Collection
+var jwtSecret []byte
+var authenticator Authenticator
+
+////////
+// SETUP
+////////
+
+// Setup - setup the collection to be used for users and the authentication settings
+func Setup(client *mongo.Client, config *Config) {
+	userColl = client.Database("csesoc").Collection("users")
+
+	// Creating unique index for user IDs, an index for looking up refresh tokens
+	// and one for listing users by role
+	indexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "userID", Value: 1}},
+			Options: options.Index().SetName("userID_1").SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "refreshToken", Value: 1}},
+			Options: options.Index().SetName("refreshToken_1"),
+		},
+		{
+			Keys:    bson.D{{Key: "role", Value: 1}, {Key: "firstName", Value: 1}},
+			Options: options.Index().SetName("role_1_firstName_1"),
+		},
+	}
+	if err := EnsureIndexes(userColl, indexes); err != nil {
+		log.Fatal("Could not create index: ", err)
+	}
+	jwtSecret = config.JWTSecret
+	authenticator = &LDAPAuthenticator{Host: config.LDAPHost}
+	if config.TempLogin {
+		log.Println("Warning: TEMP_LOGIN is set, accepting the test accounts instead of LDAP")
+		authenticator = &tempAuthenticator{}
 	}
 
-	// Attempt to sign in using credentials
-	hashedZID := sha256.Sum256([]byte(zid))
-	stringZID := string(hashedZID[:])
-	username := zid + "ad.unsw.edu.au"
+	// Admin is only ever granted through the role stored against a user
+	for _, zid := range config.AdminZIDs {
+		if err := grantAdmin(zid); err != nil {
+			log.Fatal("Could not grant admin to ", zid, ": ", err)
+		}
+	}
+}
 
-	err = l.Bind(username, password)
-	if err != nil {
-		log.Fatal(err)
+///////////
+// HANDLERS
+///////////
+
+// HandleLogin godoc
+// @Summary Exchange a zID and password for an access token and a refresh token
+// @Tags auth
+// @Accept x-www-form-urlencoded
+// @Param zid formData string true "zID, e.g. z1234567"
+// @Param password formData string true "zPass"
+// @Success 200 {object} utility.H "token and refreshToken"
+// @Failure 401 {string} error "Invalid credentials"
+// @Failure 429 {string} error "Too many requests"
+// @Failure 500 {string} error "Unable to log in"
+// @Failure 503 {string} error "Authentication server unavailable"
+// @Router /login [post]
+func HandleLogin(c echo.Context) error {
+	ctx, cancel := DBContext(c)
+	defer cancel()
+
+	token, refreshToken, err := Auth(ctx, c.FormValue("zid"), c.FormValue("password"))
+	switch err {
+	case nil:
+		return c.JSON(http.StatusOK, H{
+			"token":        token,
+			"refreshToken": refreshToken,
+		})
+	case errInvalidCredentials:
+		return Unauthorized(err.Error())
+	case errLDAPUnavailable:
+		LogError(c, err)
+		return Unavailable(err.Error())
+	default:
+		LogError(c, err)
+		return Internal("Unable to log in")
 	}
+}
 
-	// Retrieve first name from Identity Manager
-	baseDN := "OU=IDM_People,OU=IDM,DC=ad,DC=unsw,DC=edu,DC=au"
-	searchScope := ldap.ScopeWholeSubtree
-	aliases := ldap.NeverDerefAliases
-	retrieveAttributes := []string{"givenName"}
-	searchFilter := "cn=" + username //cn = common name
+// HandleRefresh godoc
+// @Summary Exchange a refresh token for a new access token without logging in again
+// @Tags auth
+// @Accept x-www-form-urlencoded
+// @Param refreshToken formData string true "Refresh token returned by /login"
+// @Success 200 {object} utility.H "token"
+// @Failure 401 {string} error "Invalid or expired refresh token"
+// @Failure 500 {string} error "Unable to refresh token"
+// @Router /token/refresh [post]
+func HandleRefresh(c echo.Context) error {
+	refreshToken := c.FormValue("refreshToken")
+	if refreshToken == "" {
+		return Unauthorized("Invalid or expired refresh token")
+	}
 
-	searchRequest := ldap.NewSearchRequest(
-		baseDN, searchScope, aliases, 0, 0, false,
-		searchFilter, retrieveAttributes, nil,
-	)
+	ctx, cancel := DBContext(c)
+	defer cancel()
 
-	searchResult, err := l.Search(searchRequest)
+	var user User
+	filter := bson.D{
+		{Key: "refreshToken", Value: hashRefreshToken(refreshToken)},
+		{Key: "refreshExpiry", Value: bson.D{{Key: "$gt", Value: time.Now().Unix()}}},
+	}
+	if err := userColl.FindOne(ctx, filter).Decode(&user); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return Unauthorized("Invalid or expired refresh token")
+		}
+		return Internal("Unable to refresh token")
+	}
+
+	token, err := signAccessToken(user.UserID, user.FirstName, user.Role, user.TokenVersion)
 	if err != nil {
-		log.Fatal(err)
+		return Internal("Unable to refresh token")
 	}
 
-	// Encode user details into a JWT and turn it into a string
+	update := bson.D{
+		{Key: "$set", Value: bson.D{
+			{Key: "userToken", Value: token},
+		}},
+	}
+	if _, err := userColl.UpdateOne(ctx, bson.D{{Key: "userID", Value: user.UserID}}, update); err != nil {
+		return Internal("Unable to refresh token")
+	}
+	return c.JSON(http.StatusOK, H{
+		"token": token,
+	})
+}
 
-	userFound := searchResult.Entries[0]
-	expirationTime := time.Now().Add(time.Hour * 24)
-	claims := &Claims{
-		HashedZID:   hashedZID,
-		FirstName:   userFound.GetAttributeValue("firstName"),
-		Permissions: permissions,
-		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: expirationTime.Unix(),
-		},
+// HandleLogout godoc
+// @Summary Log out, revoking every access and refresh token of the user
+// @Description Must be chained after RequireAuth.
+// @Tags auth
+// @Param Authorization header string true "Bearer <token>"
+// @Success 200 {object} utility.H "response"
+// @Failure 401 {string} error "Invalid or missing token"
+// @Failure 500 {string} error "Unable to log out"
+// @Router /logout [post]
+// @Security BearerAuthKey
+func HandleLogout(c echo.Context) error {
+	claims, ok := GetClaims(c)
+	if !ok {
+		return Unauthorized("Invalid or missing token")
 	}
-	tokenJWT := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, _ := tokenJWT.SignedString(jwtKey)
 
-	// Insert a new user into the collection if user has never logged in before
-	// Or update the existing token if it has expired
-	user := User{
-		UserID:    stringZID,
-		UserToken: tokenString,
-		Role:      "user", // Change this???
+	ctx, cancel := DBContext(c)
+	defer cancel()
+
+	filter := bson.D{{Key: "userID", Value: claims.HashedZID}}
+	update := bson.D{
+		{Key: "$set", Value: bson.D{
+			{Key: "userToken", Value: ""},
+			{Key: "refreshToken", Value: ""},
+			{Key: "refreshExpiry", Value: 0},
+		}},
+		// Revoke every access token issued so far
+		{Key: "$inc", Value: bson.D{{Key: "tokenVersion", Value: 1}}},
 	}
+	if _, err := userColl.UpdateOne(ctx, filter, update); err != nil {
+		return Internal("Unable to log out")
+	}
+	forgetTokenVersion(claims.HashedZID)
+	return c.JSON(http.StatusOK, H{
+		"response": "Logged out",
+	})
+}
 
-	var isValidUser *User
-	userFilter := bson.D{{Key: "userID", Value: stringZID}}
-	err = collection.FindOne(context.TODO(), userFilter).Decode(&isValidUser)
+// HandleMe godoc
+// @Summary Get the profile of the authenticated user from their token
+// @Description Must be chained after RequireAuth.
+// @Tags auth
+// @Param Authorization header string true "Bearer <token>"
+// @Success 200 {object} utility.H "id, firstName and role"
+// @Failure 401 {string} error "Invalid or missing token"
+// @Router /me [get]
+// @Security BearerAuthKey
+func HandleMe(c echo.Context) error {
+	claims, ok := GetClaims(c)
+	if !ok {
+		return Unauthorized("Invalid or missing token")
+	}
+	return c.JSON(http.StatusOK, H{
+		"id":        claims.HashedZID,
+		"firstName": claims.FirstName,
+		"role":      claims.Role,
+	})
+}
 
-	if isValidUser == nil { // Never logged in before
-		_, err = collection.InsertOne(context.TODO(), user)
-		if err != nil {
-			log.Fatal(err)
-		}
-	} else { // Logged in before - check validity of token
-		claims = &Claims{}
-		decodedToken, _ := jwt.ParseWithClaims(isValidUser.UserToken, claims, func(token *jwt.Token) (interface{}, error) {
-			return jwtKey, nil
-		})
-		decodedTokenString, _ := decodedToken.SignedString(jwtKey)
-
-		if !decodedToken.Valid { // Logged in before but token is invalid - replace with new token
-			filter := bson.D{{Key: "userID", Value: stringZID}}
-			update := bson.D{
-				{Key: "$set", Value: bson.D{
-					{Key: "userToken", Value: decodedTokenString},
-				}},
-			}
-			_, err = collection.UpdateOne(context.TODO(), filter, update)
-			if err != nil {
-				log.Fatal(err)
-			}
+// HandleGetUsers godoc
+// @Summary Get a page of the users who have logged in, ordered by first name
+// @Tags users
+// @Param Authorization header string true "Bearer <token>"
+// @Param role query string false "Only list users with this role" Enums(user, admin)
+// @Param limit query integer false "Maximum number of users to return" mininum(1) maxinum(100)
+// @Param offset query integer false "Number of users to skip" mininum(0)
+// @Success 200 {object} utility.Page{data=[]User}
+// @Failure 400 {string} error "Invalid role, expected user or admin"
+// @Failure 400 {string} error "Invalid limit or offset"
+// @Failure 401 {string} error "Invalid or missing token"
+// @Failure 403 {string} error "Insufficient permissions"
+// @Failure 500 {string} error "Unable to retrieve users from database"
+// @Router /api/v1/users [get]
+// @Security BearerAuthKey
+func HandleGetUsers(c echo.Context) error {
+	filter := bson.D{}
+	if role := c.QueryParam("role"); role != "" {
+		if role != RoleUser && role != RoleAdmin {
+			return BadRequest("Invalid role, expected user or admin")
 		}
+		filter = append(filter, bson.E{Key: "role", Value: role})
+	}
+	limit, offset, err := ParsePagination(c)
+	if err != nil {
+		return BadRequest("Invalid limit or offset")
 	}
 
-	return tokenString
-}
+	ctx, cancel := DBContext(c)
+	defer cancel()
+	total, err := userColl.CountDocuments(ctx, filter)
+	if err != nil {
+		return Internal("Unable to retrieve users from database")
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "firstName", Value: 1}, {Key: "userID", Value: 1}}).
+		SetLimit(limit).
+		SetSkip(offset)
+	cursor, err := userColl.Find(ctx, filter, opts)
+	if err != nil {
+		return Internal("Unable to retrieve users from database")
+	}
+	// Tokens are left out of the JSON of a User
+	users := []User{}
+	if err := cursor.All(ctx, &users); err != nil {
+		return Internal("Unable to retrieve users from database")
+	}
 
-// validToken - returns true if a token is valid and false otherwise.
-func validToken(tokenString string) bool {
-	claims := &Claims{}
-	tkn, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		return jwtKey, nil
+	return c.JSON(http.StatusOK, Page{
+		Data:   users,
+		Total:  total,
+		Limit:  limit,
+		Offset: offset,
 	})
+}
+
+//////////
+// HELPERS
+//////////
+
+// grantAdmin - stores the admin role against a zID, creating the user if they have never logged in
+func grantAdmin(zid string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
 
+	filter := bson.D{{Key: "userID", Value: hashZID(zid)}}
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: "role", Value: RoleAdmin}}}}
+	_, err := userColl.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	return err
+}
+
+// Auth - authenticates a zID and returns a new access and refresh token
+func Auth(ctx context.Context, zid string, password string) (string, string, error) {
+	firstName, err := authenticator.Authenticate(zid, password)
 	if err != nil {
-		if err == jwt.ErrSignatureInvalid {
-			return false
-		}
+		return "", "", err
 	}
+	hashedZID := hashZID(zid)
 
-	if !tkn.Valid || claims.Permissions != "staff" {
-		return false
+	// Returning users keep the role stored against them
+	var isValidUser *User
+	userFilter := bson.D{{Key: "userID", Value: hashedZID}}
+	err = userColl.FindOne(ctx, userFilter).Decode(&isValidUser)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return "", "", err
+	}
+	role, version := RoleUser, 0
+	if isValidUser != nil {
+		role, version = isValidUser.Role, isValidUser.TokenVersion
+	}
+
+	// Encode user details into a JWT and turn it into a string
+	tokenString, err := signAccessToken(hashedZID, firstName, role, version)
+	if err != nil {
+		return "", "", err
+	}
+	refreshToken, refreshHash, err := newRefreshToken()
+	if err != nil {
+		return "", "", err
+	}
+
+	// Insert a new user into the collection if user has never logged in before
+	// Or store the new tokens against the existing user
+	update := bson.D{
+		{Key: "$set", Value: bson.D{
+			{Key: "userToken", Value: tokenString},
+			{Key: "firstName", Value: firstName},
+			{Key: "refreshToken", Value: refreshHash},
+			{Key: "refreshExpiry", Value: time.Now().Add(refreshTokenLifetime).Unix()},
+		}},
+		{Key: "$setOnInsert", Value: bson.D{
+			{Key: "role", Value: role},
+		}},
+	}
+	opts := options.Update().SetUpsert(true)
+	_, err = userColl.UpdateOne(ctx, userFilter, update, opts)
+	if IsDuplicateKey(err) {
+		// A concurrent first login inserted the user first, so this attempt will update them
+		_, err = userColl.UpdateOne(ctx, userFilter, update, opts)
+	}
+	if err != nil {
+		return "", "", err
 	}
 
-	return true
+	return tokenString, refreshToken, nil
 }
@@ -0,0 +1,444 @@
+package login
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/labstack/echo/v4"
+
+	. "csesoc.unsw.edu.au/m/v2/server"
+)
+
+func TestLDAPQuery(t *testing.T) {
+	t.Run("Bind username is a UPN", func(t *testing.T) {
+		AssertResponseBody(t, bindUsername("z1234567"), "z1234567@ad.unsw.edu.au")
+	})
+
+	t.Run("Search filter matches the zID", func(t *testing.T) {
+		AssertResponseBody(t, searchFilter("z1234567"), "(cn=z1234567)")
+		AssertResponseBody(t, searchFilter("z1234567)(cn=*"), `(cn=z1234567\29\28cn=\2a)`)
+	})
+}
+
+// fakeAuthenticator - accepts a single zID and password without contacting LDAP
+type fakeAuthenticator struct {
+	zid       string
+	password  string
+	firstName string
+	err       error
+}
+
+func (a *fakeAuthenticator) Authenticate(zid string, password string) (string, error) {
+	if a.err != nil {
+		return "", a.err
+	}
+	if zid != a.zid || password != a.password {
+		return "", errInvalidCredentials
+	}
+	return a.firstName, nil
+}
+
+func TestAuth(t *testing.T) {
+	authenticator = &fakeAuthenticator{zid: "z1234567", password: "hunter2", firstName: "Alex"}
+
+	t.Run("Wrong password is rejected", func(t *testing.T) {
+		_, _, err := Auth(context.Background(), "z1234567", "wrong")
+		if err != errInvalidCredentials {
+			t.Errorf("Expected invalid credentials, got %v", err)
+		}
+	})
+
+	t.Run("Unreachable LDAP is reported", func(t *testing.T) {
+		authenticator = &fakeAuthenticator{err: errLDAPUnavailable}
+		_, _, err := Auth(context.Background(), "z1234567", "hunter2")
+		if err != errLDAPUnavailable {
+			t.Errorf("Expected LDAP to be unavailable, got %v", err)
+		}
+	})
+}
+
+func TestLDAPAuthenticator(t *testing.T) {
+	t.Run("Empty password is rejected without binding", func(t *testing.T) {
+		ldapAuth := &LDAPAuthenticator{Host: "localhost:0"}
+		if _, err := ldapAuth.Authenticate("z1234567", ""); err != errInvalidCredentials {
+			t.Errorf("Expected invalid credentials, got %v", err)
+		}
+	})
+}
+
+func TestTempAuthenticator(t *testing.T) {
+	tempAuth := &tempAuthenticator{}
+
+	t.Run("Test account is accepted", func(t *testing.T) {
+		firstName, err := tempAuth.Authenticate("z5123456", tempUsers["z5123456"])
+		if err != nil {
+			t.Errorf("Could not authenticate: %v", err)
+		}
+		AssertResponseBody(t, firstName, "Test")
+	})
+
+	t.Run("Wrong password is rejected", func(t *testing.T) {
+		if _, err := tempAuth.Authenticate("z5123456", "wrong"); err != errInvalidCredentials {
+			t.Errorf("Expected invalid credentials, got %v", err)
+		}
+	})
+}
+
+func TestHashZID(t *testing.T) {
+	t.Run("Stored user IDs are 64 character hex strings", func(t *testing.T) {
+		hashedZID := hashZID("z1234567")
+		if len(hashedZID) != 64 {
+			t.Errorf("Expected 64 characters, got %d", len(hashedZID))
+		}
+		if _, err := hex.DecodeString(hashedZID); err != nil {
+			t.Errorf("Expected a hex string, got %q", hashedZID)
+		}
+		AssertResponseBody(t, hashZID("z1234567"), hashedZID)
+	})
+}
+
+func TestClaims(t *testing.T) {
+	t.Run("Claims survive signing and parsing", func(t *testing.T) {
+		jwtSecret = []byte("test_secret")
+		hashedZID := hashZID("z1234567")
+
+		token, err := signAccessToken(hashedZID, "Alex", RoleAdmin, 2)
+		if err != nil {
+			t.Errorf("Could not sign token: %v", err)
+			return
+		}
+
+		claims := &Claims{}
+		_, err = jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
+			return jwtSecret, nil
+		})
+		if err != nil {
+			t.Errorf("Could not parse token: %v", err)
+			return
+		}
+		AssertResponseBody(t, claims.HashedZID, hashedZID)
+		AssertResponseBody(t, claims.FirstName, "Alex")
+		AssertResponseBody(t, claims.Role, RoleAdmin)
+		AssertResponseBody(t, strconv.Itoa(claims.Version), "2")
+	})
+}
+
+func TestUserJSON(t *testing.T) {
+	t.Run("Tokens are never sent to clients", func(t *testing.T) {
+		body, _ := json.Marshal(User{UserID: "id", UserToken: "token", Role: RoleUser, RefreshToken: "refresh"})
+		AssertResponseBody(t, string(body), `{"userID":"id","role":"user","firstName":""}`)
+	})
+}
+
+func TestRequireAuth(t *testing.T) {
+	jwtSecret = []byte("test_secret")
+	storedVersion := 0
+	defer func(previous func(context.Context, string) (int, error)) { currentTokenVersion = previous }(currentTokenVersion)
+	currentTokenVersion = func(ctx context.Context, hashedZID string) (int, error) {
+		return storedVersion, nil
+	}
+	serve := func(authorization string) *httptest.ResponseRecorder {
+		e := echo.New()
+		e.HTTPErrorHandler = HTTPErrorHandler
+		e.GET("/", func(c echo.Context) error {
+			claims, _ := GetClaims(c)
+			return c.String(http.StatusOK, claims.FirstName)
+		}, RequireAuth())
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(echo.HeaderAuthorization, authorization)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+	token, err := signAccessToken(hashZID("z1234567"), "Alex", RoleUser, 0)
+	if err != nil {
+		t.Errorf("Could not sign token: %v", err)
+		return
+	}
+
+	t.Run("Valid token is accepted", func(t *testing.T) {
+		rec := serve("Bearer " + token)
+		AssertStatus(t, rec.Code, http.StatusOK)
+		AssertResponseBody(t, rec.Body.String(), "Alex")
+	})
+
+	t.Run("Malformed headers are rejected", func(t *testing.T) {
+		for _, authorization := range []string{
+			"",
+			token,
+			"Basic " + token,
+			"Bearer ",
+			"Bearer " + token + "." + token,
+			"Bearer a..b",
+			"Bearer a.b.c=",
+			"Bearer " + strings.Repeat("a", maxTokenLength) + ".b.c",
+		} {
+			AssertStatus(t, serve(authorization).Code, http.StatusUnauthorized)
+		}
+	})
+
+	sign := func(claims jwt.StandardClaims) string {
+		token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, &Claims{StandardClaims: claims}).SignedString(jwtSecret)
+		return token
+	}
+
+	t.Run("Expired token is rejected as expired", func(t *testing.T) {
+		rec := serve("Bearer " + sign(jwt.StandardClaims{
+			ExpiresAt: time.Now().Add(-time.Minute).Unix(),
+		}))
+		AssertStatus(t, rec.Code, http.StatusUnauthorized)
+		AssertResponseBody(t, rec.Body.String(), `{"error":{"code":"unauthorized","message":"Token expired"}}`+"\n")
+	})
+
+	t.Run("Token that is not valid yet is rejected", func(t *testing.T) {
+		rec := serve("Bearer " + sign(jwt.StandardClaims{
+			ExpiresAt: time.Now().Add(time.Hour).Unix(),
+			NotBefore: time.Now().Add(time.Minute).Unix(),
+		}))
+		AssertStatus(t, rec.Code, http.StatusUnauthorized)
+		AssertResponseBody(t, rec.Body.String(), `{"error":{"code":"unauthorized","message":"Invalid or missing token"}}`+"\n")
+	})
+
+	t.Run("Token without an expiry is rejected", func(t *testing.T) {
+		AssertStatus(t, serve("Bearer "+sign(jwt.StandardClaims{})).Code, http.StatusUnauthorized)
+	})
+
+	t.Run("Expired token with a bad signature is not reported as expired", func(t *testing.T) {
+		expired := sign(jwt.StandardClaims{ExpiresAt: time.Now().Add(-time.Minute).Unix()})
+		rec := serve("Bearer " + expired[:len(expired)-2] + "AA")
+		AssertResponseBody(t, rec.Body.String(), `{"error":{"code":"unauthorized","message":"Invalid or missing token"}}`+"\n")
+	})
+
+	t.Run("Token issued before logging out is rejected", func(t *testing.T) {
+		storedVersion = 1
+		forgetTokenVersion(hashZID("z1234567"))
+		defer func() {
+			storedVersion = 0
+			forgetTokenVersion(hashZID("z1234567"))
+		}()
+		AssertStatus(t, serve("Bearer "+token).Code, http.StatusUnauthorized)
+	})
+
+	t.Run("Token versions are cached between requests", func(t *testing.T) {
+		forgetTokenVersion(hashZID("z1234567"))
+		lookups := 0
+		defer func(previous func(context.Context, string) (int, error)) { currentTokenVersion = previous }(currentTokenVersion)
+		currentTokenVersion = func(ctx context.Context, hashedZID string) (int, error) {
+			lookups++
+			return storedVersion, nil
+		}
+		for i := 0; i < 3; i++ {
+			AssertStatus(t, serve("Bearer "+token).Code, http.StatusOK)
+		}
+		if lookups != 1 {
+			t.Errorf("Expected the token version to be read once, read %d times", lookups)
+		}
+	})
+
+	t.Run("Token signed with another secret is rejected", func(t *testing.T) {
+		jwtSecret = []byte("another_secret")
+		defer func() { jwtSecret = []byte("test_secret") }()
+		AssertStatus(t, serve("Bearer "+token).Code, http.StatusUnauthorized)
+	})
+}
+
+func TestOptionalAuth(t *testing.T) {
+	jwtSecret = []byte("test_secret")
+	defer func(previous func(context.Context, string) (int, error)) { currentTokenVersion = previous }(currentTokenVersion)
+	currentTokenVersion = func(ctx context.Context, hashedZID string) (int, error) {
+		return 0, nil
+	}
+	serve := func(authorization string) *httptest.ResponseRecorder {
+		e := echo.New()
+		e.HTTPErrorHandler = HTTPErrorHandler
+		e.GET("/", func(c echo.Context) error {
+			if claims, ok := GetClaims(c); ok {
+				return c.String(http.StatusOK, claims.Role)
+			}
+			return c.String(http.StatusOK, "anonymous")
+		}, OptionalAuth())
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if authorization != "" {
+			req.Header.Set(echo.HeaderAuthorization, authorization)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("Anonymous callers are let through without claims", func(t *testing.T) {
+		rec := serve("")
+		AssertStatus(t, rec.Code, http.StatusOK)
+		AssertResponseBody(t, rec.Body.String(), "anonymous")
+	})
+
+	t.Run("Callers with a token are authenticated", func(t *testing.T) {
+		token, err := signAccessToken(hashZID("z1234567"), "Alex", RoleAdmin, 0)
+		if err != nil {
+			t.Errorf("Could not sign token: %v", err)
+			return
+		}
+		rec := serve("Bearer " + token)
+		AssertStatus(t, rec.Code, http.StatusOK)
+		AssertResponseBody(t, rec.Body.String(), RoleAdmin)
+	})
+
+	t.Run("Invalid tokens are still rejected", func(t *testing.T) {
+		AssertStatus(t, serve("Bearer a.b.c").Code, http.StatusUnauthorized)
+	})
+}
+
+func TestLogout(t *testing.T) {
+	t.Run("Logout without a token", func(t *testing.T) {
+		resp, err := http.Post(BASE_URL+LOGOUT_URL, "", nil)
+		if err != nil {
+			t.Errorf("Could not perform POST request: %v", err)
+			return
+		}
+		defer resp.Body.Close()
+
+		AssertStatus(t, resp.StatusCode, http.StatusUnauthorized)
+	})
+
+	t.Run("Token is rejected after logging out", func(t *testing.T) {
+		// Logging out revokes every token of the user, so use another account than AUTH_TOKEN's
+		form := url.Values{
+			"zid":      {"z5654321"},
+			"password": {tempUsers["z5654321"]},
+		}
+		resp, err := http.PostForm(BASE_URL+LOGIN_URL, form)
+		if err != nil {
+			t.Errorf("Could not perform POST request: %v", err)
+			return
+		}
+		defer resp.Body.Close()
+		AssertStatus(t, resp.StatusCode, http.StatusOK)
+
+		var login map[string]string
+		if err := json.NewDecoder(resp.Body).Decode(&login); err != nil {
+			t.Errorf("Error parsing JSON response: %v", err)
+			return
+		}
+		authorization := "Bearer " + login["token"]
+
+		client := &http.Client{}
+		for _, expected := range []int{http.StatusOK, http.StatusUnauthorized} {
+			req, _ := http.NewRequest("POST", BASE_URL+LOGOUT_URL, nil)
+			req.Header.Add("Authorization", authorization)
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Errorf("Could not perform POST request: %v", err)
+				return
+			}
+			resp.Body.Close()
+
+			AssertStatus(t, resp.StatusCode, expected)
+		}
+
+		req, _ := http.NewRequest("GET", BASE_URL+ME_URL, nil)
+		req.Header.Add("Authorization", authorization)
+		resp, err = client.Do(req)
+		if err != nil {
+			t.Errorf("Could not perform GET request: %v", err)
+			return
+		}
+		defer resp.Body.Close()
+
+		AssertStatus(t, resp.StatusCode, http.StatusUnauthorized)
+	})
+}
+
+func TestRefresh(t *testing.T) {
+	t.Run("Refresh with an unknown token", func(t *testing.T) {
+		form := url.Values{
+			"refreshToken": {"not-a-refresh-token"},
+		}
+		resp, err := http.PostForm(BASE_URL+REFRESH_URL, form)
+		if err != nil {
+			t.Errorf("Could not perform POST request: %v", err)
+			return
+		}
+		defer resp.Body.Close()
+
+		AssertStatus(t, resp.StatusCode, http.StatusUnauthorized)
+	})
+}
+
+func TestMe(t *testing.T) {
+	t.Run("Profile without a token", func(t *testing.T) {
+		resp, err := http.Get(BASE_URL + ME_URL)
+		if err != nil {
+			t.Errorf("Could not perform GET request: %v", err)
+			return
+		}
+		defer resp.Body.Close()
+
+		AssertStatus(t, resp.StatusCode, http.StatusUnauthorized)
+	})
+
+	t.Run("Profile with a token", func(t *testing.T) {
+		client := &http.Client{}
+		req, _ := http.NewRequest("GET", BASE_URL+ME_URL, nil)
+		req.Header.Add("Authorization", AUTH_TOKEN)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Errorf("Could not perform GET request: %v", err)
+			return
+		}
+		defer resp.Body.Close()
+
+		AssertStatus(t, resp.StatusCode, http.StatusOK)
+	})
+}
+
+func TestUsers(t *testing.T) {
+	t.Run("Users without a token", func(t *testing.T) {
+		resp, err := http.Get(BASE_URL + USERS_URL)
+		if err != nil {
+			t.Errorf("Could not perform GET request: %v", err)
+			return
+		}
+		defer resp.Body.Close()
+
+		AssertStatus(t, resp.StatusCode, http.StatusUnauthorized)
+	})
+
+	t.Run("Admins can list users by role", func(t *testing.T) {
+		client := &http.Client{}
+		req, _ := http.NewRequest("GET", BASE_URL+USERS_URL+"?role="+RoleAdmin, nil)
+		req.Header.Add("Authorization", AUTH_TOKEN)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Errorf("Could not perform GET request: %v", err)
+			return
+		}
+		defer resp.Body.Close()
+
+		AssertStatus(t, resp.StatusCode, http.StatusOK)
+	})
+
+	t.Run("Invalid role", func(t *testing.T) {
+		client := &http.Client{}
+		req, _ := http.NewRequest("GET", BASE_URL+USERS_URL+"?role=owner", nil)
+		req.Header.Add("Authorization", AUTH_TOKEN)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Errorf("Could not perform GET request: %v", err)
+			return
+		}
+		defer resp.Body.Close()
+
+		AssertStatus(t, resp.StatusCode, http.StatusBadRequest)
+	})
+}
@@ -90,9 +90,7 @@ func handleMessage(c echo.Context, mt messageType) error {
 		}
 		// Validate struct
 		if err := c.Validate(enquiry); err != nil {
-			return c.JSON(http.StatusBadRequest, H{
-				"error": "Invalid form",
-			})
+			return BadRequest("Invalid form")
 		}
 	} else if mt == feedbackType {
 		feedback = Feedback{
@@ -102,9 +100,7 @@ func handleMessage(c echo.Context, mt messageType) error {
 		}
 		// Validate struct
 		if err := c.Validate(feedback); err != nil {
-			return c.JSON(http.StatusBadRequest, H{
-				"error": "Invalid form",
-			})
+			return BadRequest("Invalid form")
 		}
 	}
 
@@ -130,7 +126,8 @@ func handleMessage(c echo.Context, mt messageType) error {
 // @Success 202 "Accepted"
 // @Header 202 {string} response "Enquiry added to dispatch bundle"
 // @Failure 400 {string} error "Invalid form"
-// @Router /mailing/general [post]
+// @Failure 413 {string} error "Request Entity Too Large"
+// @Router /api/v1/mailing/general [post]
 func HandleGeneralMessage(c echo.Context) error {
 	return handleMessage(c, generalType)
 }
@@ -144,7 +141,8 @@ func HandleGeneralMessage(c echo.Context) error {
 // @Success 202 "Accepted"
 // @Header 202 {string} response "Enquiry added to dispatch bundle"
 // @Failure 400 {string} error "Invalid form"
-// @Router /mailing/sponsorship [post]
+// @Failure 413 {string} error "Request Entity Too Large"
+// @Router /api/v1/mailing/sponsorship [post]
 func HandleSponsorshipMessage(c echo.Context) error {
 	return handleMessage(c, sponsorshipType)
 }
@@ -158,7 +156,8 @@ func HandleSponsorshipMessage(c echo.Context) error {
 // @Success 202 "Accepted"
 // @Header 202 {string} response "Feedback added to dispatch bundle"
 // @Failure 400 {string} error "Invalid form"
-// @Router /mailing/feedback [post]
+// @Failure 413 {string} error "Request Entity Too Large"
+// @Router /api/v1/mailing/feedback [post]
 func HandleFeedbackMessage(c echo.Context) error {
 	return handleMessage(c, feedbackType)
 }
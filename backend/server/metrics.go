@@ -0,0 +1,188 @@
+/*
+  Metrics
+  --
+  This file records request and database metrics and exposes them in the Prometheus
+  text format. Requests are labelled by their route rather than their path so that
+  URL parameters don't create a new series for every sponsor or resource.
+*/
+
+package utility
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/event"
+)
+
+type (
+	// requestKey - labels of the request counter
+	requestKey struct {
+		method string
+		route  string
+		status string
+	}
+
+	// durationKey - labels of the request duration histogram
+	durationKey struct {
+		method string
+		route  string
+	}
+
+	// histogram - cumulative counts of observations under each bucket
+	histogram struct {
+		buckets []uint64
+		sum     float64
+		count   uint64
+	}
+)
+
+// durationBuckets - upper bounds in seconds of the request duration histogram
+var durationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+var (
+	metricsMu sync.Mutex
+	requests  = map[requestKey]uint64{}
+	durations = map[durationKey]*histogram{}
+
+	dbConnectionsOpen  int64
+	dbConnectionsInUse int64
+)
+
+// DBPoolMonitor - tracks the connections held by a mongo client, set with ClientOptions.SetPoolMonitor
+var DBPoolMonitor = &event.PoolMonitor{
+	Event: func(e *event.PoolEvent) {
+		switch e.Type {
+		case event.ConnectionCreated:
+			atomic.AddInt64(&dbConnectionsOpen, 1)
+		case event.ConnectionClosed:
+			atomic.AddInt64(&dbConnectionsOpen, -1)
+		case event.GetSucceeded:
+			atomic.AddInt64(&dbConnectionsInUse, 1)
+		case event.ConnectionReturned:
+			atomic.AddInt64(&dbConnectionsInUse, -1)
+		}
+	},
+}
+
+// Metrics - middleware counting requests and timing them by route and status
+func Metrics() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) (err error) {
+			start := time.Now()
+			// Let the error handler write the response so its status is recorded
+			if err = next(c); err != nil {
+				c.Error(err)
+			}
+
+			route := c.Path()
+			if route == "" {
+				route = "unmatched"
+			}
+			observeRequest(c.Request().Method, route, c.Response().Status, time.Since(start))
+			return err
+		}
+	}
+}
+
+// HandleMetrics godoc
+// @Summary Get request counts and latencies in the Prometheus text format
+// @Tags metrics
+// @Produce plain
+// @Success 200 {string} string "Prometheus metrics"
+// @Router /metrics [get]
+func HandleMetrics(c echo.Context) error {
+	c.Response().Header().Set(echo.HeaderContentType, "text/plain; version=0.0.4")
+	c.Response().WriteHeader(http.StatusOK)
+	writeMetrics(c.Response())
+	return nil
+}
+
+// observeRequest - records a single finished request
+func observeRequest(method string, route string, status int, duration time.Duration) {
+	metricsMu.Lock()
+	defer metricsMu.Unlock()
+
+	requests[requestKey{method, route, strconv.Itoa(status)}]++
+
+	key := durationKey{method, route}
+	h, ok := durations[key]
+	if !ok {
+		h = &histogram{buckets: make([]uint64, len(durationBuckets))}
+		durations[key] = h
+	}
+	seconds := duration.Seconds()
+	for i, bound := range durationBuckets {
+		if seconds <= bound {
+			h.buckets[i]++
+		}
+	}
+	h.sum += seconds
+	h.count++
+}
+
+// writeMetrics - writes every metric, with series sorted so the output is stable
+func writeMetrics(w io.Writer) {
+	metricsMu.Lock()
+	defer metricsMu.Unlock()
+
+	fmt.Fprintln(w, "# HELP http_requests_total Total HTTP requests by route and status.")
+	fmt.Fprintln(w, "# TYPE http_requests_total counter")
+	requestKeys := make([]requestKey, 0, len(requests))
+	for key := range requests {
+		requestKeys = append(requestKeys, key)
+	}
+	sort.Slice(requestKeys, func(i, j int) bool {
+		a, b := requestKeys[i], requestKeys[j]
+		if a.route != b.route {
+			return a.route < b.route
+		}
+		if a.method != b.method {
+			return a.method < b.method
+		}
+		return a.status < b.status
+	})
+	for _, key := range requestKeys {
+		fmt.Fprintf(w, "http_requests_total{method=%q,route=%q,status=%q} %d\n",
+			key.method, key.route, key.status, requests[key])
+	}
+
+	fmt.Fprintln(w, "# HELP http_request_duration_seconds HTTP request latencies by route.")
+	fmt.Fprintln(w, "# TYPE http_request_duration_seconds histogram")
+	durationKeys := make([]durationKey, 0, len(durations))
+	for key := range durations {
+		durationKeys = append(durationKeys, key)
+	}
+	sort.Slice(durationKeys, func(i, j int) bool {
+		a, b := durationKeys[i], durationKeys[j]
+		if a.route != b.route {
+			return a.route < b.route
+		}
+		return a.method < b.method
+	})
+	for _, key := range durationKeys {
+		h := durations[key]
+		for i, bound := range durationBuckets {
+			fmt.Fprintf(w, "http_request_duration_seconds_bucket{method=%q,route=%q,le=%q} %d\n",
+				key.method, key.route, strconv.FormatFloat(bound, 'g', -1, 64), h.buckets[i])
+		}
+		fmt.Fprintf(w, "http_request_duration_seconds_bucket{method=%q,route=%q,le=\"+Inf\"} %d\n",
+			key.method, key.route, h.count)
+		fmt.Fprintf(w, "http_request_duration_seconds_sum{method=%q,route=%q} %g\n", key.method, key.route, h.sum)
+		fmt.Fprintf(w, "http_request_duration_seconds_count{method=%q,route=%q} %d\n", key.method, key.route, h.count)
+	}
+
+	fmt.Fprintln(w, "# HELP mongodb_connections_open Connections held open to MongoDB.")
+	fmt.Fprintln(w, "# TYPE mongodb_connections_open gauge")
+	fmt.Fprintf(w, "mongodb_connections_open %d\n", atomic.LoadInt64(&dbConnectionsOpen))
+	fmt.Fprintln(w, "# HELP mongodb_connections_in_use Connections to MongoDB checked out by a request.")
+	fmt.Fprintln(w, "# TYPE mongodb_connections_in_use gauge")
+	fmt.Fprintf(w, "mongodb_connections_in_use %d\n", atomic.LoadInt64(&dbConnectionsInUse))
+}
@@ -0,0 +1,30 @@
+package utility
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetrics(t *testing.T) {
+	t.Run("Requests are counted by route and status", func(t *testing.T) {
+		observeRequest("GET", "/api/v1/sponsors/:name", 404, 20*time.Millisecond)
+		observeRequest("GET", "/api/v1/sponsors/:name", 404, 20*time.Millisecond)
+
+		var out bytes.Buffer
+		writeMetrics(&out)
+		expected := []string{
+			`http_requests_total{method="GET",route="/api/v1/sponsors/:name",status="404"} 2`,
+			`http_request_duration_seconds_bucket{method="GET",route="/api/v1/sponsors/:name",le="0.01"} 0`,
+			`http_request_duration_seconds_bucket{method="GET",route="/api/v1/sponsors/:name",le="0.025"} 2`,
+			`http_request_duration_seconds_count{method="GET",route="/api/v1/sponsors/:name"} 2`,
+			"mongodb_connections_open 0",
+		}
+		for _, line := range expected {
+			if !strings.Contains(out.String(), line+"\n") {
+				t.Errorf("Missing metric %s", line)
+			}
+		}
+	})
+}
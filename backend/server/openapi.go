@@ -0,0 +1,257 @@
+/*
+  OpenAPI
+  --
+  This file converts the Swagger 2.0 spec swag generates from the handler annotations
+  into an OpenAPI 3 document, which is what /openapi.json serves. Only the parts of
+  Swagger 2.0 swag emits are handled:
+  - body and formData parameters become request bodies
+  - response schemas are wrapped in their content types
+  - definitions and securityDefinitions move under components
+*/
+
+package utility
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// openAPIVersion - version of the OpenAPI specification documents are converted to
+const openAPIVersion = "3.0.3"
+
+// parameterSchemaKeys - fields of a Swagger 2.0 parameter that describe its value
+var parameterSchemaKeys = []string{
+	"type", "format", "items", "enum", "default",
+	"minimum", "maximum", "minLength", "maxLength", "pattern",
+}
+
+// OpenAPI3 - converts a Swagger 2.0 spec into an OpenAPI 3 document
+func OpenAPI3(swagger []byte) ([]byte, error) {
+	var spec map[string]interface{}
+	if err := json.Unmarshal(swagger, &spec); err != nil {
+		return nil, err
+	}
+
+	doc := map[string]interface{}{
+		"openapi": openAPIVersion,
+		"info":    convertInfo(spec["info"]),
+		"servers": []interface{}{map[string]interface{}{"url": serverURL(spec)}},
+	}
+
+	paths := map[string]interface{}{}
+	for path, item := range asMap(spec["paths"]) {
+		operations := map[string]interface{}{}
+		for method, operation := range asMap(item) {
+			operations[method] = convertOperation(asMap(operation), spec)
+		}
+		paths[path] = operations
+	}
+	doc["paths"] = paths
+
+	components := map[string]interface{}{}
+	if definitions, ok := spec["definitions"]; ok {
+		components["schemas"] = definitions
+	}
+	if securityDefinitions, ok := spec["securityDefinitions"]; ok {
+		components["securitySchemes"] = securityDefinitions
+	}
+	if len(components) > 0 {
+		doc["components"] = components
+	}
+
+	return json.Marshal(rewriteRefs(doc))
+}
+
+// convertInfo - drops the empty license swag adds, as OpenAPI 3 requires licenses to be named
+func convertInfo(info interface{}) map[string]interface{} {
+	converted := map[string]interface{}{}
+	for key, value := range asMap(info) {
+		converted[key] = value
+	}
+	if license := asMap(converted["license"]); license["name"] == nil {
+		delete(converted, "license")
+	}
+	return converted
+}
+
+// serverURL - joins the host and base path of a Swagger 2.0 spec, relative when there is no host
+func serverURL(spec map[string]interface{}) string {
+	basePath, _ := spec["basePath"].(string)
+	host, _ := spec["host"].(string)
+	if host == "" {
+		if basePath == "" {
+			return "/"
+		}
+		return basePath
+	}
+	scheme := "https"
+	if schemes, ok := spec["schemes"].([]interface{}); ok && len(schemes) > 0 {
+		scheme, _ = schemes[0].(string)
+	}
+	return scheme + "://" + host + basePath
+}
+
+// convertOperation - moves body and form parameters into a request body and wraps response schemas
+func convertOperation(operation map[string]interface{}, spec map[string]interface{}) map[string]interface{} {
+	converted := map[string]interface{}{}
+	for key, value := range operation {
+		switch key {
+		case "consumes", "produces", "parameters", "responses":
+		default:
+			converted[key] = value
+		}
+	}
+
+	parameters := []interface{}{}
+	formProperties := map[string]interface{}{}
+	formRequired := []interface{}{}
+	formMediaType := echo.MIMEApplicationForm
+	for _, param := range asSlice(operation["parameters"]) {
+		param := asMap(param)
+		switch param["in"] {
+		case "body":
+			content := map[string]interface{}{}
+			for _, mediaType := range mediaTypes(operation["consumes"], spec["consumes"], echo.MIMEApplicationJSON) {
+				content[mediaType] = map[string]interface{}{"schema": param["schema"]}
+			}
+			converted["requestBody"] = withDescription(map[string]interface{}{
+				"content":  content,
+				"required": param["required"] == true,
+			}, param)
+		case "formData":
+			property := parameterSchema(param)
+			if param["type"] == "file" {
+				property = map[string]interface{}{"type": "string", "format": "binary"}
+				formMediaType = echo.MIMEMultipartForm
+			}
+			if description, ok := param["description"]; ok {
+				property["description"] = description
+			}
+			formProperties[param["name"].(string)] = property
+			if param["required"] == true {
+				formRequired = append(formRequired, param["name"])
+			}
+		default:
+			parameters = append(parameters, withDescription(map[string]interface{}{
+				"name":     param["name"],
+				"in":       param["in"],
+				"required": param["required"] == true || param["in"] == "path",
+				"schema":   parameterSchema(param),
+			}, param))
+		}
+	}
+	if len(parameters) > 0 {
+		converted["parameters"] = parameters
+	}
+
+	if len(formProperties) > 0 {
+		schema := map[string]interface{}{
+			"type":       "object",
+			"properties": formProperties,
+		}
+		if len(formRequired) > 0 {
+			schema["required"] = formRequired
+		}
+		content := map[string]interface{}{}
+		for _, mediaType := range mediaTypes(operation["consumes"], spec["consumes"], formMediaType) {
+			content[mediaType] = map[string]interface{}{"schema": schema}
+		}
+		converted["requestBody"] = map[string]interface{}{
+			"content":  content,
+			"required": len(formRequired) > 0,
+		}
+	}
+
+	produces := mediaTypes(operation["produces"], spec["produces"], echo.MIMEApplicationJSON)
+	responses := map[string]interface{}{}
+	for status, response := range asMap(operation["responses"]) {
+		response := asMap(response)
+		convertedResponse := map[string]interface{}{
+			"description": response["description"],
+		}
+		if schema, ok := response["schema"]; ok {
+			content := map[string]interface{}{}
+			for _, mediaType := range produces {
+				content[mediaType] = map[string]interface{}{"schema": schema}
+			}
+			convertedResponse["content"] = content
+		}
+		if headers, ok := response["headers"]; ok {
+			convertedResponse["headers"] = headers
+		}
+		responses[status] = convertedResponse
+	}
+	converted["responses"] = responses
+
+	return converted
+}
+
+// parameterSchema - collects the fields describing a parameter's value into a schema
+func parameterSchema(param map[string]interface{}) map[string]interface{} {
+	schema := map[string]interface{}{}
+	for _, key := range parameterSchemaKeys {
+		if value, ok := param[key]; ok {
+			schema[key] = value
+		}
+	}
+	return schema
+}
+
+// withDescription - copies the description of a Swagger 2.0 parameter, if it has one
+func withDescription(converted map[string]interface{}, param map[string]interface{}) map[string]interface{} {
+	if description, ok := param["description"]; ok {
+		converted["description"] = description
+	}
+	return converted
+}
+
+// mediaTypes - returns the media types of an operation, falling back to the spec's and then fallback
+func mediaTypes(operation interface{}, spec interface{}, fallback string) []string {
+	for _, list := range []interface{}{operation, spec} {
+		types := []string{}
+		for _, mediaType := range asSlice(list) {
+			if mediaType, ok := mediaType.(string); ok {
+				types = append(types, mediaType)
+			}
+		}
+		if len(types) > 0 {
+			return types
+		}
+	}
+	return []string{fallback}
+}
+
+// rewriteRefs - points references to definitions at their new place under components
+func rewriteRefs(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for key, child := range value {
+			if ref, ok := child.(string); ok && key == "$ref" {
+				value[key] = strings.Replace(ref, "#/definitions/", "#/components/schemas/", 1)
+			} else {
+				value[key] = rewriteRefs(child)
+			}
+		}
+	case []interface{}:
+		for i, child := range value {
+			value[i] = rewriteRefs(child)
+		}
+	}
+	return value
+}
+
+// asMap - returns value as a JSON object, or an empty one if it isn't
+func asMap(value interface{}) map[string]interface{} {
+	if m, ok := value.(map[string]interface{}); ok {
+		return m
+	}
+	return map[string]interface{}{}
+}
+
+// asSlice - returns value as a JSON array, or nil if it isn't
+func asSlice(value interface{}) []interface{} {
+	s, _ := value.([]interface{})
+	return s
+}
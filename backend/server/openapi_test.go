@@ -0,0 +1,95 @@
+package utility
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestOpenAPI3(t *testing.T) {
+	swagger := `{
+		"swagger": "2.0",
+		"info": {"title": "API", "version": "1.0", "license": {}},
+		"basePath": "/",
+		"paths": {
+			"/sponsors/{name}/logo": {
+				"post": {
+					"consumes": ["multipart/form-data"],
+					"parameters": [
+						{"type": "string", "name": "name", "in": "path", "required": true},
+						{"type": "file", "name": "logo", "in": "formData", "required": true}
+					],
+					"responses": {
+						"200": {"description": "OK", "schema": {"$ref": "#/definitions/sponsor.Sponsor"}},
+						"204": {"description": "No content"}
+					}
+				}
+			},
+			"/mailing/general": {
+				"post": {
+					"parameters": [
+						{"type": "string", "name": "body", "in": "formData", "required": true}
+					],
+					"responses": {}
+				}
+			}
+		},
+		"definitions": {"sponsor.Sponsor": {"type": "object"}},
+		"securityDefinitions": {"BearerAuthKey": {"type": "apiKey", "name": "Authorization", "in": "header"}}
+	}`
+
+	converted, err := OpenAPI3([]byte(swagger))
+	if err != nil {
+		t.Errorf("Could not convert spec: %v", err)
+		return
+	}
+	var doc struct {
+		OpenAPI    string                            `json:"openapi"`
+		Info       map[string]interface{}            `json:"info"`
+		Servers    []map[string]string               `json:"servers"`
+		Paths      map[string]map[string]H           `json:"paths"`
+		Components map[string]map[string]interface{} `json:"components"`
+	}
+	if err := json.Unmarshal(converted, &doc); err != nil {
+		t.Errorf("Could not parse converted spec: %v", err)
+		return
+	}
+	logo := doc.Paths["/sponsors/{name}/logo"]["post"]
+	encode := func(value interface{}) string {
+		body, _ := json.Marshal(value)
+		return string(body)
+	}
+
+	t.Run("Document is OpenAPI 3", func(t *testing.T) {
+		AssertResponseBody(t, doc.OpenAPI, "3.0.3")
+		AssertResponseBody(t, doc.Servers[0]["url"], "/")
+		if _, ok := doc.Info["license"]; ok {
+			t.Errorf("Expected the unnamed license to be dropped")
+		}
+	})
+
+	t.Run("Parameters carry a schema", func(t *testing.T) {
+		AssertResponseBody(t, encode(logo["parameters"]),
+			`[{"in":"path","name":"name","required":true,"schema":{"type":"string"}}]`)
+	})
+
+	t.Run("Form parameters become a request body", func(t *testing.T) {
+		AssertResponseBody(t, encode(logo["requestBody"]),
+			`{"content":{"multipart/form-data":{"schema":{"properties":{"logo":{"format":"binary","type":"string"}},"required":["logo"],"type":"object"}}},"required":true}`)
+		AssertResponseBody(t, encode(doc.Paths["/mailing/general"]["post"]["requestBody"]),
+			`{"content":{"application/x-www-form-urlencoded":{"schema":{"properties":{"body":{"type":"string"}},"required":["body"],"type":"object"}}},"required":true}`)
+	})
+
+	t.Run("Responses wrap their schema in content", func(t *testing.T) {
+		AssertResponseBody(t, encode(logo["responses"]),
+			`{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/sponsor.Sponsor"}}},"description":"OK"},"204":{"description":"No content"}}`)
+	})
+
+	t.Run("Definitions move under components", func(t *testing.T) {
+		if _, ok := doc.Components["schemas"]["sponsor.Sponsor"]; !ok {
+			t.Errorf("Expected sponsor.Sponsor under components.schemas")
+		}
+		if _, ok := doc.Components["securitySchemes"]["BearerAuthKey"]; !ok {
+			t.Errorf("Expected BearerAuthKey under components.securitySchemes")
+		}
+	})
+}
@@ -0,0 +1,162 @@
+/*
+  Rate Limiting
+  --
+  This file provides middleware limiting how often a single client can call a route.
+  Every client IP gets a token bucket which refills at a steady rate up to a burst size,
+  and each request spends one token. The middleware can be used globally and again on
+  individual routes or groups to give expensive endpoints a stricter limit.
+
+  Clients are told apart by c.RealIP(), so the server must set an IPExtractor, see
+  ClientIPExtractor. Echo otherwise trusts X-Forwarded-For from anyone, letting a
+  client dodge its limit by sending a different header with every request.
+*/
+
+package utility
+
+import (
+	"math"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+// sweepInterval - how often buckets of idle clients are dropped
+const sweepInterval = time.Minute
+
+type (
+	// RateLimitConfig - settings for RateLimitWithConfig
+	RateLimitConfig struct {
+		// Skipper defines a function to skip the middleware
+		Skipper middleware.Skipper
+		// Rate is the number of requests per second a client is allowed on average
+		Rate float64
+		// Burst is the number of requests a client can make at once
+		Burst int
+	}
+
+	// bucket - tokens left for a single client
+	bucket struct {
+		tokens float64
+		last   time.Time
+	}
+
+	// rateLimiter - token buckets keyed by client
+	rateLimiter struct {
+		mu        sync.Mutex
+		rate      float64 // tokens added per second
+		burst     float64
+		buckets   map[string]*bucket
+		lastSweep time.Time
+	}
+)
+
+// RateLimit - allows each client IP up to limit requests per window, responding 429 once they run out.
+// A limit of 0 lets every request through, so configured limits can be turned off.
+func RateLimit(limit int, window time.Duration) echo.MiddlewareFunc {
+	if limit == 0 {
+		return func(next echo.HandlerFunc) echo.HandlerFunc {
+			return next
+		}
+	}
+	return RateLimitWithConfig(RateLimitConfig{
+		Rate:  float64(limit) / window.Seconds(),
+		Burst: limit,
+	})
+}
+
+// RateLimitWithConfig - limits each client IP to the configured rate, responding 429
+// with a Retry-After header once they run out
+func RateLimitWithConfig(config RateLimitConfig) echo.MiddlewareFunc {
+	if config.Skipper == nil {
+		config.Skipper = middleware.DefaultSkipper
+	}
+	limiter := newRateLimiter(config.Rate, config.Burst)
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if config.Skipper(c) {
+				return next(c)
+			}
+			if ok, retryAfter := limiter.allow(c.RealIP(), time.Now()); !ok {
+				seconds := int(math.Ceil(retryAfter.Seconds()))
+				c.Response().Header().Set("Retry-After", strconv.Itoa(seconds))
+				return TooManyRequests("Too many requests")
+			}
+			return next(c)
+		}
+	}
+}
+
+// ClientIPExtractor - identifies clients by the address they connect from, or when they
+// connect through one of trustedProxies by the nearest untrusted X-Forwarded-For entry
+func ClientIPExtractor(trustedProxies []*net.IPNet) echo.IPExtractor {
+	if len(trustedProxies) == 0 {
+		return echo.ExtractIPDirect()
+	}
+	// Echo trusts loopback and private addresses by default, which any client on them could spoof
+	options := []echo.TrustOption{
+		echo.TrustLoopback(false),
+		echo.TrustLinkLocal(false),
+		echo.TrustPrivateNet(false),
+	}
+	for _, proxy := range trustedProxies {
+		options = append(options, echo.TrustIPRange(proxy))
+	}
+	return echo.ExtractIPFromXFFHeader(options...)
+}
+
+// newRateLimiter - creates a limiter refilling rate tokens per second up to burst
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	return &rateLimiter{
+		rate:    rate,
+		burst:   float64(burst),
+		buckets: map[string]*bucket{},
+	}
+}
+
+// allow - spends a token from the client's bucket, or reports how long until one is available
+func (l *rateLimiter) allow(key string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastSweep) > sweepInterval {
+		l.sweep(now)
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = l.refill(b, now)
+	b.last = now
+
+	if b.tokens < 1 {
+		wait := (1 - b.tokens) / l.rate
+		return false, time.Duration(wait * float64(time.Second))
+	}
+	b.tokens--
+	return true, 0
+}
+
+// refill - returns the tokens a bucket holds at the given time
+func (l *rateLimiter) refill(b *bucket, now time.Time) float64 {
+	tokens := b.tokens + now.Sub(b.last).Seconds()*l.rate
+	if tokens > l.burst {
+		return l.burst
+	}
+	return tokens
+}
+
+// sweep - drops full buckets, which behave the same as a client that was never seen
+func (l *rateLimiter) sweep(now time.Time) {
+	for key, b := range l.buckets {
+		if l.refill(b, now) >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastSweep = now
+}
@@ -0,0 +1,156 @@
+package utility
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestRateLimit(t *testing.T) {
+	t.Run("Requests past the limit are rejected", func(t *testing.T) {
+		limiter := newRateLimiter(1, 3)
+		now := time.Now()
+		for i := 0; i < 3; i++ {
+			if ok, _ := limiter.allow("127.0.0.1", now); !ok {
+				t.Errorf("Request %d was rejected", i+1)
+			}
+		}
+		if ok, _ := limiter.allow("127.0.0.1", now); ok {
+			t.Errorf("Request over the limit was allowed")
+		}
+	})
+
+	t.Run("Clients are limited separately", func(t *testing.T) {
+		limiter := newRateLimiter(1, 1)
+		now := time.Now()
+		limiter.allow("127.0.0.1", now)
+		if ok, _ := limiter.allow("127.0.0.2", now); !ok {
+			t.Errorf("Request from another client was rejected")
+		}
+	})
+
+	t.Run("Tokens refill over time", func(t *testing.T) {
+		limiter := newRateLimiter(1, 1)
+		now := time.Now()
+		limiter.allow("127.0.0.1", now)
+		if ok, _ := limiter.allow("127.0.0.1", now.Add(500*time.Millisecond)); ok {
+			t.Errorf("Request was allowed before a token refilled")
+		}
+		if ok, _ := limiter.allow("127.0.0.1", now.Add(time.Second)); !ok {
+			t.Errorf("Request was rejected after a token refilled")
+		}
+	})
+
+	t.Run("Rejected requests are told when to retry", func(t *testing.T) {
+		limiter := newRateLimiter(0.5, 1)
+		now := time.Now()
+		limiter.allow("127.0.0.1", now)
+		ok, retryAfter := limiter.allow("127.0.0.1", now)
+		if ok {
+			t.Errorf("Request over the limit was allowed")
+		}
+		if retryAfter != 2*time.Second {
+			t.Errorf("Expected to retry after 2s, got %v", retryAfter)
+		}
+	})
+
+	t.Run("Spoofed X-Forwarded-For does not reset the limit", func(t *testing.T) {
+		e := echo.New()
+		e.HTTPErrorHandler = HTTPErrorHandler
+		e.IPExtractor = ClientIPExtractor(nil)
+		e.Use(RateLimitWithConfig(RateLimitConfig{Rate: 1, Burst: 2}))
+		e.GET("/", func(c echo.Context) error {
+			return c.NoContent(http.StatusOK)
+		})
+
+		statuses := []int{}
+		for _, forwardedFor := range []string{"203.0.113.1", "203.0.113.2", "203.0.113.3"} {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(echo.HeaderXForwardedFor, forwardedFor)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			statuses = append(statuses, rec.Code)
+		}
+		AssertStatus(t, statuses[1], http.StatusOK)
+		AssertStatus(t, statuses[2], http.StatusTooManyRequests)
+	})
+
+	t.Run("Clients behind a trusted proxy are limited separately", func(t *testing.T) {
+		_, proxy, _ := net.ParseCIDR("192.0.2.0/24")
+		e := echo.New()
+		e.IPExtractor = ClientIPExtractor([]*net.IPNet{proxy})
+		e.Use(RateLimitWithConfig(RateLimitConfig{Rate: 1, Burst: 1}))
+		e.GET("/", func(c echo.Context) error {
+			return c.NoContent(http.StatusOK)
+		})
+
+		for _, forwardedFor := range []string{"203.0.113.1", "203.0.113.2"} {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = "192.0.2.1:1234"
+			req.Header.Set(echo.HeaderXForwardedFor, forwardedFor)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			AssertStatus(t, rec.Code, http.StatusOK)
+		}
+	})
+
+	t.Run("Route limits can't be dodged with X-Forwarded-For either", func(t *testing.T) {
+		// As used for login and the mailing forms
+		e := echo.New()
+		e.HTTPErrorHandler = HTTPErrorHandler
+		e.IPExtractor = ClientIPExtractor(nil)
+		e.POST("/login", func(c echo.Context) error {
+			return c.NoContent(http.StatusOK)
+		}, RateLimit(2, time.Minute))
+
+		statuses := []int{}
+		for _, forwardedFor := range []string{"203.0.113.1", "203.0.113.2", "203.0.113.3"} {
+			req := httptest.NewRequest(http.MethodPost, "/login", nil)
+			req.Header.Set(echo.HeaderXForwardedFor, forwardedFor)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			statuses = append(statuses, rec.Code)
+		}
+		AssertStatus(t, statuses[1], http.StatusOK)
+		AssertStatus(t, statuses[2], http.StatusTooManyRequests)
+	})
+
+	t.Run("Route limits are stricter than the global limit", func(t *testing.T) {
+		e := echo.New()
+		e.HTTPErrorHandler = HTTPErrorHandler
+		e.Use(RateLimitWithConfig(RateLimitConfig{Rate: 20, Burst: 40}))
+		ok := func(c echo.Context) error {
+			return c.NoContent(http.StatusOK)
+		}
+		e.GET("/api/v1/faq", ok)
+		mailing := e.Group("/api/v1/mailing", RateLimit(2, time.Minute))
+		mailing.POST("/general", ok)
+
+		serve := func(method string, target string) int {
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(method, target, nil))
+			return rec.Code
+		}
+		AssertStatus(t, serve(http.MethodPost, "/api/v1/mailing/general"), http.StatusOK)
+		AssertStatus(t, serve(http.MethodPost, "/api/v1/mailing/general"), http.StatusOK)
+		AssertStatus(t, serve(http.MethodPost, "/api/v1/mailing/general"), http.StatusTooManyRequests)
+		AssertStatus(t, serve(http.MethodGet, "/api/v1/faq"), http.StatusOK)
+	})
+
+	t.Run("Route limits of 0 are off", func(t *testing.T) {
+		e := echo.New()
+		e.POST("/api/v1/mailing/general", func(c echo.Context) error {
+			return c.NoContent(http.StatusOK)
+		}, RateLimit(0, time.Minute))
+
+		for i := 0; i < 20; i++ {
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/mailing/general", nil))
+			AssertStatus(t, rec.Code, http.StatusOK)
+		}
+	})
+}
@@ -0,0 +1,46 @@
+/*
+  Redirect
+  --
+  This file provides middleware sending plain HTTP requests to HTTPS. Echo's HTTPSRedirect
+  keeps the host as sent, port included, so a request to :80 would be sent to https on :80.
+  The port is replaced by the one HTTPS is served on instead, and left out when it is 443.
+*/
+
+package utility
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// defaultHTTPSPort - port browsers use for https URLs without one
+const defaultHTTPSPort = "443"
+
+// HTTPSRedirect - permanently redirects requests to the same host and path on httpsPort
+func HTTPSRedirect(httpsPort string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if c.IsTLS() {
+				return next(c)
+			}
+
+			req := c.Request()
+			host := req.Host
+			if hostname, _, err := net.SplitHostPort(host); err == nil {
+				host = hostname
+			} else {
+				host = strings.Trim(host, "[]")
+			}
+			if httpsPort != "" && httpsPort != defaultHTTPSPort {
+				host = net.JoinHostPort(host, httpsPort)
+			} else if strings.Contains(host, ":") {
+				// IPv6 addresses are bracketed in URLs even without a port
+				host = "[" + host + "]"
+			}
+			return c.Redirect(http.StatusMovedPermanently, "https://"+host+req.RequestURI)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package utility
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestHTTPSRedirect(t *testing.T) {
+	redirect := func(httpsPort string, host string, target string) *httptest.ResponseRecorder {
+		e := echo.New()
+		e.Pre(HTTPSRedirect(httpsPort))
+
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Host = host
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("The request port is replaced by the HTTPS port", func(t *testing.T) {
+		rec := redirect("8443", "example.com:8080", "/api/v1/sponsors?limit=5")
+		AssertStatus(t, rec.Code, http.StatusMovedPermanently)
+		AssertResponseBody(t, rec.Header().Get(echo.HeaderLocation), "https://example.com:8443/api/v1/sponsors?limit=5")
+	})
+
+	t.Run("The default HTTPS port is left out", func(t *testing.T) {
+		rec := redirect("443", "example.com:80", "/")
+		AssertStatus(t, rec.Code, http.StatusMovedPermanently)
+		AssertResponseBody(t, rec.Header().Get(echo.HeaderLocation), "https://example.com/")
+	})
+
+	t.Run("Hosts without a port are redirected", func(t *testing.T) {
+		rec := redirect("443", "example.com", "/healthz")
+		AssertResponseBody(t, rec.Header().Get(echo.HeaderLocation), "https://example.com/healthz")
+	})
+
+	t.Run("IPv6 hosts stay bracketed", func(t *testing.T) {
+		rec := redirect("443", "[::1]:80", "/")
+		AssertResponseBody(t, rec.Header().Get(echo.HeaderLocation), "https://[::1]/")
+		rec = redirect("443", "[::1]", "/")
+		AssertResponseBody(t, rec.Header().Get(echo.HeaderLocation), "https://[::1]/")
+		rec = redirect("8443", "[::1]:80", "/")
+		AssertResponseBody(t, rec.Header().Get(echo.HeaderLocation), "https://[::1]:8443/")
+	})
+}
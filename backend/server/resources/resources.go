@@ -43,13 +43,13 @@ func Setup(client *mongo.Client) {
 	resourceColl = client.Database("csesoc").Collection("resources")
 
 	// Creating unique index for resource title
-	opt := options.Index()
-	opt.SetUnique(true)
-	index := mongo.IndexModel{
-		Keys:    bson.M{"title": 1},
-		Options: opt,
+	indexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "title", Value: 1}},
+			Options: options.Index().SetName("title_1").SetUnique(true),
+		},
 	}
-	if _, err := resourceColl.Indexes().CreateOne(context.Background(), index); err != nil {
+	if err := EnsureIndexes(resourceColl, indexes); err != nil {
 		log.Fatal("Could not create index: ", err)
 	}
 
@@ -75,20 +75,23 @@ func Setup(client *mongo.Client) {
 // @Tags resources
 // @Success 200 {array} Resource
 // @Failure 500 {string} error "Unable to retrieve resources from database"
-// @Router /resources/preview [get]
+// @Router /api/v1/resources/preview [get]
 func HandleGetPreview(c echo.Context) error {
 	var results []*Resource
 
+	ctx, cancel := DBContext(c)
+	defer cancel()
+
 	// get database pointer
-	curr, err := resourceColl.Find(context.TODO(), bson.D{{}}, options.Find())
+	curr, err := resourceColl.Find(ctx, bson.D{{}}, options.Find())
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, H{
-			"error": "Unable to retrieve resources from database",
-		})
+		return Internal("Unable to retrieve resources from database")
 	}
 
+	defer curr.Close(ctx)
+
 	// decode result into resource array
-	for curr.Next(context.TODO()) {
+	for curr.Next(ctx) {
 		var elem Resource
 		curr.Decode(&elem)
 		results = append(results, &elem)
@@ -41,14 +41,14 @@ var socialColl *mongo.Collection
 func Setup(client *mongo.Client) {
 	socialColl = client.Database("csesoc").Collection("socials")
 
-	// Creating unique index for sponsor name
-	opt := options.Index()
-	opt.SetUnique(true)
-	index := mongo.IndexModel{
-		Keys:    bson.M{"title": 1},
-		Options: opt,
+	// Creating unique index for social link title
+	indexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "title", Value: 1}},
+			Options: options.Index().SetName("title_1").SetUnique(true),
+		},
 	}
-	if _, err := socialColl.Indexes().CreateOne(context.Background(), index); err != nil {
+	if err := EnsureIndexes(socialColl, indexes); err != nil {
 		log.Fatal("Could not create index: ", err)
 	}
 
@@ -79,22 +79,20 @@ func Setup(client *mongo.Client) {
 // @Success 200 {array} Social
 // @Failure 500 {string} error "Missing fields"
 // @Failure 503 {string} error "Unable to retrieve social media links"
-// @Router /social [get]
+// @Router /api/v1/social [get]
 func HandleGet(c echo.Context) error {
-	socials, err := retrieveSocials()
+	ctx, cancel := DBContext(c)
+	defer cancel()
+	socials, err := retrieveSocials(ctx)
 
 	if err != nil {
-		return c.JSON(http.StatusServiceUnavailable, H{
-			"error": "Unable to retrieve social links from database",
-		})
+		return Unavailable("Unable to retrieve social links from database")
 	}
 
 	// Validate structss
 	for _, social := range socials {
 		if err := c.Validate(social); err != nil {
-			return c.JSON(http.StatusInternalServerError, H{
-				"error": fmt.Sprintf("Missing fields on: %v", social),
-			})
+			return Internal(fmt.Sprintf("Missing fields on: %v", social))
 		}
 	}
 
@@ -105,13 +103,14 @@ func HandleGet(c echo.Context) error {
 // HELPERS
 //////////
 
-func retrieveSocials() ([]*Social, error) {
+func retrieveSocials(ctx context.Context) ([]*Social, error) {
 	var results []*Social
 
-	curr, err := socialColl.Find(context.TODO(), bson.M{})
+	curr, err := socialColl.Find(ctx, bson.M{})
 	// decode result into social links array
 	if err == nil {
-		for curr.Next(context.TODO()) {
+		defer curr.Close(ctx)
+		for curr.Next(ctx) {
 			var elem Social
 			curr.Decode(&elem)
 			results = append(results, &elem)
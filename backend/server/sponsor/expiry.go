@@ -0,0 +1,57 @@
+/*
+  Expiry
+  --
+  This file handles the expiry of sponsors. Expiries are sent and returned as RFC3339
+  times but stored as unix timestamps so they can be compared directly in queries.
+*/
+
+package sponsor
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Expiry - unix time a sponsor stops being listed, 0 never expires
+type Expiry int64
+
+// errInvalidExpiry - the expiry sent was not an RFC3339 time
+var errInvalidExpiry = errors.New("Invalid expiry, expected an RFC3339 time such as 2006-01-02T15:04:05Z")
+
+// UnmarshalParam - parses an expiry sent as a form value
+func (e *Expiry) UnmarshalParam(param string) error {
+	if param == "" {
+		*e = 0
+		return nil
+	}
+	expiry, err := time.Parse(time.RFC3339, param)
+	if err != nil {
+		return errInvalidExpiry
+	}
+	*e = Expiry(expiry.Unix())
+	return nil
+}
+
+// UnmarshalJSON - parses an expiry sent in a JSON body
+func (e *Expiry) UnmarshalJSON(data []byte) error {
+	var param *string
+	if err := json.Unmarshal(data, &param); err != nil {
+		return errInvalidExpiry
+	}
+	if param == nil {
+		*e = 0
+		return nil
+	}
+	return e.UnmarshalParam(*param)
+}
+
+// MarshalJSON - returns the expiry as an RFC3339 time
+func (e Expiry) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Unix(int64(e), 0).UTC().Format(time.RFC3339))
+}
+
+// hasPassed - whether the expiry is set and at or before the given time
+func (e Expiry) hasPassed(now time.Time) bool {
+	return e != 0 && int64(e) <= now.Unix()
+}
@@ -0,0 +1,132 @@
+/*
+  Logo
+  --
+  This file handles sponsor logos uploaded by admins. Uploads are stored on local disk
+  under a content addressed name, so a file is never overwritten with a different image,
+  and served statically from LOGO_URL.
+*/
+
+package sponsor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	. "csesoc.unsw.edu.au/m/v2/server"
+	"csesoc.unsw.edu.au/m/v2/server/audit"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// maxLogoSize - largest logo accepted, in bytes
+const maxLogoSize = 512 << 10
+
+// logoExtensions - image types accepted as logos, keyed by their sniffed content type.
+// SVGs are not accepted as they can carry scripts.
+var logoExtensions = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// logoDir - directory logos are stored in, empty when uploads are disabled
+var logoDir string
+
+// LogoUploadsEnabled - whether Setup was able to prepare the logo directory
+func LogoUploadsEnabled() bool {
+	return logoDir != ""
+}
+
+// HandleUploadLogo godoc
+// @Summary Upload the logo of an existing sponsor
+// @Tags sponsors
+// @Accept mpfd
+// @Param Authorization header string true "Bearer <token>"
+// @Param name path string true "Sponsor name"
+// @Param logo formData file true "PNG, JPEG, GIF or WebP image of at most 512KB"
+// @Success 200 {object} Sponsor
+// @Failure 400 {string} error "Logo must be a PNG, JPEG, GIF or WebP image"
+// @Failure 400 {string} error "Logo must be at most 512KB"
+// @Failure 401 {string} error "Invalid or missing token"
+// @Failure 403 {string} error "Insufficient permissions"
+// @Failure 404 {string} error "No such sponsor"
+// @Failure 500 {string} error "Unable to store logo"
+// @Router /api/v1/sponsors/{name}/logo [post]
+// @Security BearerAuthKey
+func HandleUploadLogo(c echo.Context) error {
+	file, err := c.FormFile("logo")
+	if err != nil {
+		return BadRequest("Missing logo file")
+	}
+	if file.Size > maxLogoSize {
+		return BadRequest("Logo must be at most 512KB")
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return BadRequest("Missing logo file")
+	}
+	defer src.Close()
+	data, err := ioutil.ReadAll(io.LimitReader(src, maxLogoSize+1))
+	if err != nil {
+		return Internal("Unable to store logo")
+	}
+	if len(data) > maxLogoSize {
+		return BadRequest("Logo must be at most 512KB")
+	}
+
+	// Trust the bytes rather than the Content-Type sent by the client
+	extension, ok := logoExtensions[http.DetectContentType(data)]
+	if !ok {
+		return BadRequest("Logo must be a PNG, JPEG, GIF or WebP image")
+	}
+
+	ctx, cancel := DBContext(c)
+	defer cancel()
+	var sponsor Sponsor
+	filter := bson.D{{Key: "name", Value: c.Param("name")}}
+	if err := sponsorColl.FindOne(ctx, filter).Decode(&sponsor); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return NotFound("No such sponsor")
+		}
+		return Internal("Unable to store logo")
+	}
+
+	filename, err := storeLogo(data, extension)
+	if err != nil {
+		LogError(c, err)
+		return Internal("Unable to store logo")
+	}
+
+	sponsor.Logo = "/" + LOGO_URL + "/" + filename
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: "logo", Value: sponsor.Logo}}}}
+	if _, err := sponsorColl.UpdateOne(ctx, filter, update); err != nil {
+		return Internal("Unable to store logo")
+	}
+	audit.Record(c, audit.ActionUpdate, sponsorColl.Name(), sponsor.Name)
+	return c.JSON(http.StatusOK, sponsor)
+}
+
+// storeLogo - writes a logo to the logo directory, returning its file name
+func storeLogo(data []byte, extension string) (string, error) {
+	sum := sha256.Sum256(data)
+	filename := hex.EncodeToString(sum[:]) + extension
+	path := filepath.Join(logoDir, filename)
+
+	// Identical uploads share a file
+	if _, err := os.Stat(path); err == nil {
+		return filename, nil
+	}
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		return "", err
+	}
+	return filename, nil
+}
@@ -12,12 +12,17 @@ package sponsor
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
 	"strconv"
+	"time"
 
 	. "csesoc.unsw.edu.au/m/v2/server"
+	"csesoc.unsw.edu.au/m/v2/server/audit"
+	"csesoc.unsw.edu.au/m/v2/server/login"
 
 	"github.com/labstack/echo/v4"
 	"go.mongodb.org/mongo-driver/bson"
@@ -27,31 +32,54 @@ import (
 
 // Sponsor - struct to contain sponsor data
 type Sponsor struct {
-	Name   string `json:"name" validate:"required"`
-	Logo   string `json:"logo" validate:"required"`
-	Tier   int    `json:"tier" validate:"required,numeric,eq=0|eq=1|eq=2"`
-	Detail string `json:"detail" validate:"required"`
-	URL    string `json:"url" validate:"required,url"`
+	Name   string `bson:"name" json:"name" form:"name" validate:"required"`
+	Logo   string `bson:"logo" json:"logo" form:"logo" validate:"required"`
+	Tier   int    `bson:"tier" json:"tier" form:"tier" validate:"numeric"`
+	Detail string `bson:"detail" json:"detail" form:"detail" validate:"required"`
+	URL    string `bson:"url" json:"url" form:"url" validate:"required,url"`
+	Expiry Expiry `bson:"expiry,omitempty" json:"expiry,omitempty" form:"expiry"`
 }
 
+// validTiers - tiers the frontend groups sponsors into, higher tiers are displayed first
+var validTiers = []int{0, 1, 2}
+
+// invalidTierError - response to a tier outside of validTiers
+const invalidTierError = "Invalid tier, expected one of 0, 1, 2"
+
 var sponsorColl *mongo.Collection
 
 ////////
 // SETUP
 ////////
 
-// Setup - setup the collection to be used for sponsors
-func Setup(client *mongo.Client) {
+// Setup - setup the collection to be used for sponsors and the directory for their logos
+func Setup(client *mongo.Client, config *Config) {
 	sponsorColl = client.Database("csesoc").Collection("sponsors")
 
-	// Creating unique index for sponsor name
-	opt := options.Index()
-	opt.SetUnique(true)
-	index := mongo.IndexModel{
-		Keys:    bson.M{"name": 1},
-		Options: opt,
+	// Sponsors can still be listed without anywhere to store logos, so only uploads are disabled
+	if err := os.MkdirAll(config.LogoDir, 0755); err != nil {
+		log.Printf("Could not create logo directory, logo uploads are disabled: %v", err)
+	} else {
+		logoDir = config.LogoDir
 	}
-	if _, err := sponsorColl.Indexes().CreateOne(context.Background(), index); err != nil {
+
+	// Creating unique index for sponsor name, and indexes for listing by tier
+	// and purging by expiry
+	indexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "name", Value: 1}},
+			Options: options.Index().SetName("name_1").SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "tier", Value: -1}, {Key: "name", Value: 1}},
+			Options: options.Index().SetName("tier_-1_name_1"),
+		},
+		{
+			Keys:    bson.D{{Key: "expiry", Value: 1}},
+			Options: options.Index().SetName("expiry_1"),
+		},
+	}
+	if err := EnsureIndexes(sponsorColl, indexes); err != nil {
 		log.Fatal("Could not create index: ", err)
 	}
 
@@ -78,49 +106,59 @@ func Setup(client *mongo.Client) {
 // HandleNew godoc
 // @Summary Add a new sponsor
 // @Tags sponsors
-// @accept Content-Type application/x-www-form-urlencoded
+// @Accept x-www-form-urlencoded
+// @Accept json
 // @Param Authorization header string true "Bearer <token>"
 // @Param name formData string true "Name"
 // @Param logo formData string true "Logo in base64"
 // @Param tier formData integer true "Valid tier" mininum(0) maxinum(2)
 // @Param detail formData string true "Detail"
-// @Success 201 "Created"
-// @Header 201 {string} response "Sponsor added"
+// @Param url formData string true "URL"
+// @Param expiry formData string false "RFC3339 time the sponsor stops being listed"
+// @Success 201 {object} Sponsor
 // @Failure 400 {string} error "Invalid form"
+// @Failure 400 {string} error "Invalid expiry, expected an RFC3339 time such as 2006-01-02T15:04:05Z"
+// @Failure 400 {string} error "Expiry must be in the future"
+// @Failure 400 {string} error "Invalid tier, expected one of 0, 1, 2"
+// @Failure 401 {string} error "Invalid or missing token"
+// @Failure 403 {string} error "Insufficient permissions"
 // @Failure 409 {string} error "Sponsor already exists on database"
-// @Router /sponsors [post]
+// @Failure 413 {string} error "Request Entity Too Large"
+// @Failure 500 {string} error "Unable to add sponsor to database"
+// @Router /api/v1/sponsors [post]
 // @Security BearerAuthKey
 func HandleNew(c echo.Context) error {
-	tier, err := strconv.Atoi(c.FormValue("tier"))
-	if err != nil {
-		return c.JSON(http.StatusBadRequest, H{
-			"error": "Tier is not a number",
-		})
+	// Accepts both JSON and form encoded bodies
+	var sponsor Sponsor
+	if err := c.Bind(&sponsor); err != nil {
+		return BadRequest(bindError(err))
 	}
-	sponsor := Sponsor{
-		Name:   c.FormValue("name"),
-		Logo:   c.FormValue("logo"),
-		Tier:   tier,
-		Detail: c.FormValue("detail"),
-		URL:    c.FormValue("url"),
+
+	// An expired sponsor would never be listed
+	if sponsor.Expiry.hasPassed(time.Now()) {
+		return BadRequest("Expiry must be in the future")
+	}
+
+	if !isValidTier(sponsor.Tier) {
+		return BadRequest(invalidTierError)
 	}
 
 	// Validate the struct with golang validator package
 	if err := c.Validate(sponsor); err != nil {
-		return c.JSON(http.StatusBadRequest, H{
-			"error": "Invalid form",
-		})
+		return BadRequest("Invalid form")
 	}
 
-	if _, err := sponsorColl.InsertOne(context.TODO(), sponsor); err != nil {
-		return c.JSON(http.StatusConflict, H{
-			"error": "Sponsor already exists on database",
-		})
+	ctx, cancel := DBContext(c)
+	defer cancel()
+	if _, err := sponsorColl.InsertOne(ctx, sponsor); err != nil {
+		if IsDuplicateKey(err) {
+			return Conflict("Sponsor already exists on database")
+		}
+		return Internal("Unable to add sponsor to database")
 	}
+	audit.Record(c, audit.ActionCreate, sponsorColl.Name(), sponsor.Name)
 
-	return c.JSON(http.StatusCreated, H{
-		"response": "Sponsor added",
-	})
+	return c.JSON(http.StatusCreated, sponsor)
 }
 
 // HandleGetSingle godoc
@@ -129,34 +167,129 @@ func HandleNew(c echo.Context) error {
 // @Param name path string true "Sponsor name"
 // @Success 200 {object} Sponsor
 // @Failure 404 {string} error "No such sponsor"
-// @Router /sponsors/{name} [get]
+// @Failure 500 {string} error "Unable to retrieve sponsor from database"
+// @Router /api/v1/sponsors/{name} [get]
 func HandleGetSingle(c echo.Context) error {
 	var result Sponsor
 	filter := bson.D{{Key: "name", Value: c.Param("name")}}
-	if err := sponsorColl.FindOne(context.TODO(), filter).Decode(&result); err != nil {
-		return c.JSON(http.StatusNotFound, H{
-			"error": "No such sponsor",
-		})
+	ctx, cancel := DBContext(c)
+	defer cancel()
+	if err := sponsorColl.FindOne(ctx, filter).Decode(&result); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return NotFound("No such sponsor")
+		}
+		return Internal("Unable to retrieve sponsor from database")
 	}
 	return c.JSON(http.StatusOK, result)
 }
 
 // HandleGetMultiple godoc
-// @Summary Get a list of sponsors stored
+// @Summary Get a page of the sponsors stored, ordered from the highest tier down
 // @Tags sponsors
 // @Param tier query integer false "Valid sponsor tier, 0-2 inclusive" mininum(0) maxinum(2)
-// @Success 200 {array} Sponsor
+// @Param limit query integer false "Maximum number of sponsors to return" mininum(1) maxinum(100)
+// @Param offset query integer false "Number of sponsors to skip" mininum(0)
+// @Param includeExpired query boolean false "Include sponsors whose expiry has passed, admins only"
+// @Param Authorization header string false "Bearer <token>, required to include expired sponsors"
+// @Success 200 {object} utility.Page{data=[]Sponsor}
+// @Failure 400 {string} error "Tier is not a number"
+// @Failure 400 {string} error "Invalid limit or offset"
+// @Failure 401 {string} error "Invalid or missing token"
+// @Failure 403 {string} error "Only admins can list expired sponsors"
 // @Failure 500 {string} error "Unable to retrieve sponsors from database"
-// @Router /sponsors [get]
+// @Router /api/v1/sponsors [get]
 func HandleGetMultiple(c echo.Context) error {
-	tier := c.QueryParam("tier")
-	results, err := retrieveSponsors(tier)
+	filter := bson.D{}
+	if tierString := c.QueryParam("tier"); tierString != "" {
+		tier, err := strconv.Atoi(tierString)
+		if err != nil {
+			return BadRequest("Tier is not a number")
+		}
+		filter = append(filter, bson.E{Key: "tier", Value: tier})
+	}
+	if c.QueryParam("includeExpired") == "true" {
+		// Retired sponsors are only shown in the admin view
+		if claims, ok := login.GetClaims(c); !ok || claims.Role != login.RoleAdmin {
+			return Forbidden("Only admins can list expired sponsors")
+		}
+	} else {
+		filter = append(filter, bson.E{Key: "$or", Value: bson.A{
+			bson.D{{Key: "expiry", Value: bson.D{{Key: "$exists", Value: false}}}},
+			bson.D{{Key: "expiry", Value: 0}},
+			bson.D{{Key: "expiry", Value: bson.D{{Key: "$gt", Value: time.Now().Unix()}}}},
+		}})
+	}
+	limit, offset, err := ParsePagination(c)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, H{
-			"error": "Unable to retrieve sponsors from database",
-		})
+		return BadRequest("Invalid limit or offset")
 	}
-	return c.JSON(http.StatusOK, results)
+	ctx, cancel := DBContext(c)
+	defer cancel()
+	results, total, err := retrieveSponsors(ctx, filter, limit, offset)
+	if err != nil {
+		return Internal("Unable to retrieve sponsors from database")
+	}
+	return c.JSON(http.StatusOK, Page{
+		Data:   results,
+		Total:  total,
+		Limit:  limit,
+		Offset: offset,
+	})
+}
+
+// HandleUpdate godoc
+// @Summary Update the details of an existing sponsor
+// @Tags sponsors
+// @Accept x-www-form-urlencoded
+// @Accept json
+// @Param Authorization header string true "Bearer <token>"
+// @Param name path string true "Sponsor name"
+// @Param logo formData string true "Logo in base64"
+// @Param tier formData integer true "Valid tier" mininum(0) maxinum(2)
+// @Param detail formData string true "Detail"
+// @Param url formData string true "URL"
+// @Param expiry formData string false "RFC3339 time the sponsor stops being listed"
+// @Success 200 {object} Sponsor
+// @Failure 400 {string} error "Invalid form"
+// @Failure 400 {string} error "Invalid expiry, expected an RFC3339 time such as 2006-01-02T15:04:05Z"
+// @Failure 400 {string} error "Invalid tier, expected one of 0, 1, 2"
+// @Failure 401 {string} error "Invalid or missing token"
+// @Failure 403 {string} error "Insufficient permissions"
+// @Failure 404 {string} error "No such sponsor"
+// @Failure 413 {string} error "Request Entity Too Large"
+// @Failure 500 {string} error "Unable to update sponsor on database"
+// @Router /api/v1/sponsors/{name} [put]
+// @Security BearerAuthKey
+func HandleUpdate(c echo.Context) error {
+	// Accepts both JSON and form encoded bodies
+	var sponsor Sponsor
+	if err := c.Bind(&sponsor); err != nil {
+		return BadRequest(bindError(err))
+	}
+	// The name identifies the sponsor and is kept as is
+	sponsor.Name = c.Param("name")
+
+	if !isValidTier(sponsor.Tier) {
+		return BadRequest(invalidTierError)
+	}
+
+	// Validate the struct with golang validator package
+	if err := c.Validate(sponsor); err != nil {
+		return BadRequest("Invalid form")
+	}
+
+	filter := bson.D{{Key: "name", Value: sponsor.Name}}
+	ctx, cancel := DBContext(c)
+	defer cancel()
+	result, err := sponsorColl.ReplaceOne(ctx, filter, sponsor)
+	if err != nil {
+		return Internal("Unable to update sponsor on database")
+	}
+	if result.MatchedCount == 0 {
+		return NotFound("No such sponsor")
+	}
+	audit.Record(c, audit.ActionUpdate, sponsorColl.Name(), sponsor.Name)
+	return c.JSON(http.StatusOK, sponsor)
 }
 
 // HandleDelete godoc
@@ -164,49 +297,115 @@ func HandleGetMultiple(c echo.Context) error {
 // @Tags sponsors
 // @Param Authorization header string true "Bearer <token>"
 // @Param name path string true "Sponsor name"
+// @Param dryRun query boolean false "Return the sponsor that would be deleted without deleting it"
+// @Success 200 {object} Sponsor "Sponsor that would be deleted, when dryRun is true"
 // @Success 204 "No content"
-// @Header 204 {string} response "Sponsor deleted"
+// @Failure 401 {string} error "Invalid or missing token"
+// @Failure 403 {string} error "Insufficient permissions"
+// @Failure 404 {string} error "No such sponsor"
 // @Failure 500 {string} error "Unable to delete sponsor from database"
-// @Router /sponsors/{name} [delete]
+// @Router /api/v1/sponsors/{name} [delete]
 // @Security BearerAuthKey
 func HandleDelete(c echo.Context) error {
 	filter := bson.D{{Key: "name", Value: c.Param("name")}}
-	if _, err := sponsorColl.DeleteOne(context.TODO(), filter); err != nil {
-		return c.JSON(http.StatusInternalServerError, H{
-			"error": "Unable to delete sponsor from database",
-		})
+	ctx, cancel := DBContext(c)
+	defer cancel()
+
+	// Preview the delete, failing just as it would
+	if c.QueryParam("dryRun") == "true" {
+		var sponsor Sponsor
+		if err := sponsorColl.FindOne(ctx, filter).Decode(&sponsor); err != nil {
+			if err == mongo.ErrNoDocuments {
+				return NotFound("No such sponsor")
+			}
+			return Internal("Unable to delete sponsor from database")
+		}
+		return c.JSON(http.StatusOK, sponsor)
+	}
+
+	result, err := sponsorColl.DeleteOne(ctx, filter)
+	if err != nil {
+		return Internal("Unable to delete sponsor from database")
+	}
+	if result.DeletedCount == 0 {
+		return NotFound("No such sponsor")
+	}
+	audit.Record(c, audit.ActionDelete, sponsorColl.Name(), c.Param("name"))
+	return c.NoContent(http.StatusNoContent)
+}
+
+/////////
+// TIMERS
+/////////
+
+// PurgeTimer - periodically deletes sponsors that expired more than purgeAfter ago
+func PurgeTimer(purgeAfter time.Duration) {
+	const purgeInterval = time.Hour
+
+	for {
+		cutoff := time.Now().Add(-purgeAfter).Unix()
+		filter := bson.D{{Key: "expiry", Value: bson.D{
+			{Key: "$gt", Value: 0},
+			{Key: "$lt", Value: cutoff},
+		}}}
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		if result, err := sponsorColl.DeleteMany(ctx, filter); err != nil {
+			log.Printf("Could not purge expired sponsors: %v", err)
+		} else if result.DeletedCount > 0 {
+			log.Printf("Purged %d expired sponsors", result.DeletedCount)
+		}
+		cancel()
+		time.Sleep(purgeInterval)
 	}
-	return c.JSON(http.StatusNoContent, H{
-		"response": "Sponsor deleted",
-	})
 }
 
 //////////
 // HELPERS
 //////////
 
-// retrieveSponsors - Retrieve a sponsor from the database
-func retrieveSponsors(tierString string) ([]*Sponsor, error) {
-	var results []*Sponsor
+// bindError - returns the message to respond with when a sponsor could not be read from a request
+func bindError(err error) string {
+	if httpErr, ok := err.(*echo.HTTPError); ok && errors.Is(httpErr.Internal, errInvalidExpiry) {
+		return errInvalidExpiry.Error()
+	}
+	return "Invalid form"
+}
 
-	filter := bson.D{{}}
-	if tierString != "" {
-		tier, err := strconv.Atoi(tierString)
-		if err != nil {
-			return results, err
+// isValidTier - checks a tier is one the frontend can display
+func isValidTier(tier int) bool {
+	for _, valid := range validTiers {
+		if tier == valid {
+			return true
 		}
-		filter = bson.D{{Key: "tier", Value: tier}}
 	}
-	curr, err := sponsorColl.Find(context.TODO(), filter, options.Find())
+	return false
+}
+
+// retrieveSponsors - Retrieve a page of sponsors from the database along with the total matching
+func retrieveSponsors(ctx context.Context, filter bson.D, limit int64, offset int64) ([]*Sponsor, int64, error) {
+	results := []*Sponsor{}
+
+	total, err := sponsorColl.CountDocuments(ctx, filter)
+	if err != nil {
+		return results, 0, err
+	}
+
+	// Higher tiers are more important and listed first
+	opts := options.Find().
+		SetSort(bson.D{{Key: "tier", Value: -1}, {Key: "name", Value: 1}}).
+		SetLimit(limit).
+		SetSkip(offset)
+	curr, err := sponsorColl.Find(ctx, filter, opts)
 	// decode result into sponsor array
 	if err == nil {
-		for curr.Next(context.TODO()) {
+		defer curr.Close(ctx)
+		for curr.Next(ctx) {
 			var elem Sponsor
 			curr.Decode(&elem)
 			results = append(results, &elem)
 		}
 	}
-	return results, err
+	return results, total, err
 }
 
 func readSponsorsJSON() ([]Sponsor, error) {
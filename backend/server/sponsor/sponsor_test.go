@@ -1,7 +1,9 @@
 package sponsor
 
 import (
+	"bytes"
 	"encoding/json"
+	"mime/multipart"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -18,6 +20,14 @@ const companyDetail = "Example"
 const companyURL = "https://www.google.com"
 const sponsorRequestURL = BASE_URL + SPONSOR_URL
 
+// sponsorPage - paginated response of the sponsor list
+type sponsorPage struct {
+	Data   []*Sponsor `json:"data"`
+	Total  int64      `json:"total"`
+	Limit  int64      `json:"limit"`
+	Offset int64      `json:"offset"`
+}
+
 func TestSponsor(t *testing.T) {
 	t.Run("Sponsor setup test", func(t *testing.T) {
 		resp, err := http.Get(sponsorRequestURL)
@@ -28,13 +38,38 @@ func TestSponsor(t *testing.T) {
 		defer resp.Body.Close()
 
 		AssertStatus(t, resp.StatusCode, http.StatusOK)
-		var sponsors []*Sponsor
-		if err = json.NewDecoder(resp.Body).Decode(&sponsors); err != nil {
+		var page sponsorPage
+		if err = json.NewDecoder(resp.Body).Decode(&page); err != nil {
 			t.Errorf("Error parsing JSON response: %v", err)
 		}
-		if len(sponsors) == 0 {
+		sponsors := page.Data
+		if len(sponsors) == 0 || page.Total < int64(len(sponsors)) {
 			t.Errorf("Error population Sponsor list")
 		}
+		for i := 1; i < len(sponsors); i++ {
+			if sponsors[i].Tier > sponsors[i-1].Tier {
+				t.Errorf("Sponsors not ordered by tier: %s listed after %s", sponsors[i].Name, sponsors[i-1].Name)
+				break
+			}
+		}
+	})
+
+	t.Run("Testing sponsor pagination", func(t *testing.T) {
+		resp, err := http.Get(sponsorRequestURL + "?limit=2&offset=1")
+		if err != nil {
+			t.Errorf("Could not perform GET request: %v", err)
+			return
+		}
+		defer resp.Body.Close()
+
+		AssertStatus(t, resp.StatusCode, http.StatusOK)
+		var page sponsorPage
+		if err = json.NewDecoder(resp.Body).Decode(&page); err != nil {
+			t.Errorf("Error parsing JSON response: %v", err)
+		}
+		AssertResponseBody(t, strconv.Itoa(len(page.Data)), "2")
+		AssertResponseBody(t, strconv.FormatInt(page.Limit, 10), "2")
+		AssertResponseBody(t, strconv.FormatInt(page.Offset, 10), "1")
 	})
 
 	t.Run("Testing sponsor filtering", func(t *testing.T) {
@@ -48,6 +83,30 @@ func TestSponsor(t *testing.T) {
 		AssertStatus(t, resp.StatusCode, http.StatusOK)
 	})
 
+	t.Run("Testing expired sponsors included", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", sponsorRequestURL+"?includeExpired=true", nil)
+		req.Header.Add("Authorization", AUTH_TOKEN)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Errorf("Could not perform GET request: %v", err)
+			return
+		}
+		defer resp.Body.Close()
+
+		AssertStatus(t, resp.StatusCode, http.StatusOK)
+	})
+
+	t.Run("Testing expired sponsors hidden from anonymous callers", func(t *testing.T) {
+		resp, err := http.Get(sponsorRequestURL + "?includeExpired=true")
+		if err != nil {
+			t.Errorf("Could not perform GET request: %v", err)
+			return
+		}
+		defer resp.Body.Close()
+
+		AssertStatus(t, resp.StatusCode, http.StatusForbidden)
+	})
+
 	t.Run("New sponsor", func(t *testing.T) {
 		client := &http.Client{}
 		form := url.Values{
@@ -68,6 +127,14 @@ func TestSponsor(t *testing.T) {
 		defer resp.Body.Close()
 
 		AssertStatus(t, resp.StatusCode, http.StatusCreated)
+
+		var newSponsor *Sponsor
+		if err = json.NewDecoder(resp.Body).Decode(&newSponsor); err != nil {
+			t.Errorf("Error parsing JSON response: %v", err)
+		} else {
+			AssertResponseBody(t, newSponsor.Name, companyName)
+			AssertResponseBody(t, newSponsor.URL, companyURL)
+		}
 	})
 
 	t.Run("Get newly created sponsor", func(t *testing.T) {
@@ -90,6 +157,90 @@ func TestSponsor(t *testing.T) {
 		}
 	})
 
+	t.Run("Update newly created sponsor", func(t *testing.T) {
+		client := &http.Client{}
+		form := url.Values{
+			"logo":   {companyLogo},
+			"tier":   {"1"},
+			"detail": {"Updated example"},
+			"url":    {companyURL},
+		}
+		req, _ := http.NewRequest("PUT", sponsorRequestURL+"/"+companyName, strings.NewReader(form.Encode()))
+		req.Header.Add("Authorization", AUTH_TOKEN)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Errorf("Could not perform PUT request: %v", err)
+			return
+		}
+		defer resp.Body.Close()
+
+		AssertStatus(t, resp.StatusCode, http.StatusOK)
+
+		var updatedSponsor *Sponsor
+		if err = json.NewDecoder(resp.Body).Decode(&updatedSponsor); err != nil {
+			t.Errorf("Error parsing JSON response: %v", err)
+		} else {
+			AssertResponseBody(t, updatedSponsor.Name, companyName)
+			AssertResponseBody(t, updatedSponsor.Detail, "Updated example")
+			AssertResponseBody(t, strconv.Itoa(updatedSponsor.Tier), "1")
+		}
+	})
+
+	t.Run("Update newly created sponsor with JSON", func(t *testing.T) {
+		client := &http.Client{}
+		body, _ := json.Marshal(H{
+			"logo":   companyLogo,
+			"tier":   2,
+			"detail": "Updated from JSON",
+			"url":    companyURL,
+		})
+		req, _ := http.NewRequest("PUT", sponsorRequestURL+"/"+companyName, bytes.NewReader(body))
+		req.Header.Add("Authorization", AUTH_TOKEN)
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Errorf("Could not perform PUT request: %v", err)
+			return
+		}
+		defer resp.Body.Close()
+
+		AssertStatus(t, resp.StatusCode, http.StatusOK)
+
+		var updatedSponsor *Sponsor
+		if err = json.NewDecoder(resp.Body).Decode(&updatedSponsor); err != nil {
+			t.Errorf("Error parsing JSON response: %v", err)
+		} else {
+			AssertResponseBody(t, updatedSponsor.Name, companyName)
+			AssertResponseBody(t, updatedSponsor.Detail, "Updated from JSON")
+		}
+	})
+
+	t.Run("Dry run delete of newly created sponsor", func(t *testing.T) {
+		client := &http.Client{}
+		req, err := http.NewRequest("DELETE", sponsorRequestURL+"/"+companyName+"?dryRun=true", nil)
+		req.Header.Add("Authorization", AUTH_TOKEN)
+		if err != nil {
+			t.Errorf("Error crafting DELETE request: %v", err)
+			return
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Errorf("Could not perform DELETE request: %v", err)
+			return
+		}
+		defer resp.Body.Close()
+
+		AssertStatus(t, resp.StatusCode, http.StatusOK)
+
+		var sponsor *Sponsor
+		if err = json.NewDecoder(resp.Body).Decode(&sponsor); err != nil {
+			t.Errorf("Error parsing JSON response: %v", err)
+		} else {
+			AssertResponseBody(t, sponsor.Name, companyName)
+		}
+	})
+
 	t.Run("Delete newly created sponsor", func(t *testing.T) {
 		client := &http.Client{}
 		req, err := http.NewRequest("DELETE", sponsorRequestURL+"/"+companyName, nil)
@@ -172,6 +323,24 @@ func TestSponsorError(t *testing.T) {
 		AssertStatus(t, resp.StatusCode, http.StatusNoContent)
 	})
 
+	t.Run("Creating without a token", func(t *testing.T) {
+		form := url.Values{
+			"name":   {companyName},
+			"logo":   {companyLogo},
+			"tier":   {companyTier},
+			"detail": {companyDetail},
+			"url":    {companyURL},
+		}
+		resp, err := http.PostForm(sponsorRequestURL, form)
+		if err != nil {
+			t.Errorf("Could not perform POST request: %v", err)
+			return
+		}
+		defer resp.Body.Close()
+
+		AssertStatus(t, resp.StatusCode, http.StatusUnauthorized)
+	})
+
 	t.Run("Missing parameters when creating", func(t *testing.T) {
 		client := &http.Client{}
 		req, _ := http.NewRequest("POST", sponsorRequestURL, nil)
@@ -191,6 +360,163 @@ func TestSponsorError(t *testing.T) {
 		AssertStatus(t, resp.StatusCode, http.StatusBadRequest)
 	})
 
+	t.Run("Creating with a malformed expiry", func(t *testing.T) {
+		client := &http.Client{}
+		form := url.Values{
+			"name":   {companyName},
+			"logo":   {companyLogo},
+			"tier":   {companyTier},
+			"detail": {companyDetail},
+			"url":    {companyURL},
+			"expiry": {"next tuesday"},
+		}
+		req, _ := http.NewRequest("POST", sponsorRequestURL, strings.NewReader(form.Encode()))
+		req.Header.Add("Authorization", AUTH_TOKEN)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Errorf("Could not perform POST request: %v", err)
+			return
+		}
+		defer resp.Body.Close()
+
+		AssertStatus(t, resp.StatusCode, http.StatusBadRequest)
+	})
+
+	t.Run("Creating with an expiry in the past", func(t *testing.T) {
+		client := &http.Client{}
+		form := url.Values{
+			"name":   {companyName},
+			"logo":   {companyLogo},
+			"tier":   {companyTier},
+			"detail": {companyDetail},
+			"url":    {companyURL},
+			"expiry": {"2000-01-01T00:00:00Z"},
+		}
+		req, _ := http.NewRequest("POST", sponsorRequestURL, strings.NewReader(form.Encode()))
+		req.Header.Add("Authorization", AUTH_TOKEN)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Errorf("Could not perform POST request: %v", err)
+			return
+		}
+		defer resp.Body.Close()
+
+		AssertStatus(t, resp.StatusCode, http.StatusBadRequest)
+	})
+
+	t.Run("Uploading a logo that is not an image", func(t *testing.T) {
+		var body bytes.Buffer
+		writer := multipart.NewWriter(&body)
+		part, _ := writer.CreateFormFile("logo", "logo.png")
+		part.Write([]byte("<script>alert(1)</script>"))
+		writer.Close()
+
+		client := &http.Client{}
+		req, _ := http.NewRequest("POST", sponsorRequestURL+"/"+companyName+"/logo", &body)
+		req.Header.Add("Authorization", AUTH_TOKEN)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Errorf("Could not perform POST request: %v", err)
+			return
+		}
+		defer resp.Body.Close()
+
+		AssertStatus(t, resp.StatusCode, http.StatusBadRequest)
+	})
+
+	t.Run("Update with an unknown tier", func(t *testing.T) {
+		client := &http.Client{}
+		form := url.Values{
+			"logo":   {companyLogo},
+			"tier":   {"7"},
+			"detail": {companyDetail},
+			"url":    {companyURL},
+		}
+		req, _ := http.NewRequest("PUT", sponsorRequestURL+"/"+companyName, strings.NewReader(form.Encode()))
+		req.Header.Add("Authorization", AUTH_TOKEN)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Errorf("Could not perform PUT request: %v", err)
+			return
+		}
+		defer resp.Body.Close()
+
+		AssertStatus(t, resp.StatusCode, http.StatusBadRequest)
+
+		var body map[string]*AppError
+		if err = json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Errorf("Error parsing JSON response: %v", err)
+		} else {
+			AssertResponseBody(t, body["error"].Code, "bad_request")
+			AssertResponseBody(t, body["error"].Message, "Invalid tier, expected one of 0, 1, 2")
+		}
+	})
+
+	t.Run("Update non existent sponsor", func(t *testing.T) {
+		client := &http.Client{}
+		form := url.Values{
+			"logo":   {companyLogo},
+			"tier":   {companyTier},
+			"detail": {companyDetail},
+			"url":    {companyURL},
+		}
+		req, _ := http.NewRequest("PUT", sponsorRequestURL+"/nonexistent", strings.NewReader(form.Encode()))
+		req.Header.Add("Authorization", AUTH_TOKEN)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Errorf("Could not perform PUT request: %v", err)
+			return
+		}
+		defer resp.Body.Close()
+
+		AssertStatus(t, resp.StatusCode, http.StatusNotFound)
+	})
+
+	t.Run("Delete non existent sponsor", func(t *testing.T) {
+		client := &http.Client{}
+		req, err := http.NewRequest("DELETE", sponsorRequestURL+"/nonexistent", nil)
+		if err != nil {
+			t.Errorf("Error crafting DELETE request: %v", err)
+			return
+		}
+		req.Header.Add("Authorization", AUTH_TOKEN)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Errorf("Could not perform DELETE request: %v", err)
+			return
+		}
+		defer resp.Body.Close()
+
+		AssertStatus(t, resp.StatusCode, http.StatusNotFound)
+	})
+
+	t.Run("Invalid pagination", func(t *testing.T) {
+		resp, err := http.Get(sponsorRequestURL + "?limit=0")
+		if err != nil {
+			t.Errorf("Could not perform GET request: %v", err)
+			return
+		}
+		defer resp.Body.Close()
+
+		AssertStatus(t, resp.StatusCode, http.StatusBadRequest)
+	})
+
+	t.Run("Invalid tier filter", func(t *testing.T) {
+		resp, err := http.Get(sponsorRequestURL + "?tier=gold")
+		if err != nil {
+			t.Errorf("Could not perform GET request: %v", err)
+			return
+		}
+		defer resp.Body.Close()
+
+		AssertStatus(t, resp.StatusCode, http.StatusBadRequest)
+	})
+
 	t.Run("Get non existent sponsor", func(t *testing.T) {
 		resp, err := http.Get(sponsorRequestURL + "nonexistent")
 		if err != nil {
@@ -4,17 +4,27 @@
   This file contains general helper functions that are used in multiple modules.
   The categories of utilities are:
   - JSON
+  - Requests
+  - MongoDB
   - Testing
 */
 
 package utility
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
+	"log"
 	"os"
 	"path/filepath"
+	"strconv"
 	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 ///////
@@ -38,6 +48,142 @@ func ReadJSON(name string) ([]byte, error) {
 	return ioutil.ReadAll(jsonFile)
 }
 
+///////////
+// REQUESTS
+///////////
+
+// Page - envelope for paginated list responses
+type Page struct {
+	Data   interface{} `json:"data"`
+	Total  int64       `json:"total"`
+	Limit  int64       `json:"limit"`
+	Offset int64       `json:"offset"`
+}
+
+// defaultPageLimit, maxPageLimit - page size used when no limit is asked for, and the largest allowed
+var defaultPageLimit, maxPageLimit int64 = DEFAULT_PAGE_LIMIT, MAX_PAGE_LIMIT
+
+// SetPageLimits - sets the default and maximum page sizes used by ParsePagination
+func SetPageLimits(defaultLimit int64, maxLimit int64) {
+	defaultPageLimit, maxPageLimit = defaultLimit, maxLimit
+}
+
+// ParsePagination - reads the limit and offset query params, clamping the limit to the maximum page size
+func ParsePagination(c echo.Context) (int64, int64, error) {
+	limit, offset := defaultPageLimit, int64(0)
+	if param := c.QueryParam("limit"); param != "" {
+		value, err := strconv.ParseInt(param, 10, 64)
+		if err != nil || value < 1 {
+			return 0, 0, fmt.Errorf("Invalid limit: %s", param)
+		}
+		limit = value
+	}
+	if limit > maxPageLimit {
+		limit = maxPageLimit
+	}
+	if param := c.QueryParam("offset"); param != "" {
+		value, err := strconv.ParseInt(param, 10, 64)
+		if err != nil || value < 0 {
+			return 0, 0, fmt.Errorf("Invalid offset: %s", param)
+		}
+		offset = value
+	}
+	return limit, offset, nil
+}
+
+// RequestID - returns the ID assigned to the request by the RequestID middleware
+func RequestID(c echo.Context) string {
+	return c.Response().Header().Get(echo.HeaderXRequestID)
+}
+
+// LogError - logs an error against the request it happened in, so it can be traced from the X-Request-ID a client reports
+func LogError(c echo.Context, err error) {
+	c.Logger().Errorf("Request %s: %v", RequestID(c), err)
+}
+
+//////////
+// MONGODB
+//////////
+
+// dbTimeout - upper bound on the duration of a single database operation
+var dbTimeout = 5 * time.Second
+
+// SetDBTimeout - sets the timeout applied to contexts returned by DBContext
+func SetDBTimeout(timeout time.Duration) {
+	dbTimeout = timeout
+}
+
+// DBContext - derives a context for a database operation from the request context,
+// so the operation is cancelled if the client disconnects or the database stalls
+func DBContext(c echo.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(c.Request().Context(), dbTimeout)
+}
+
+// BackgroundDBContext - returns a context for a database operation that must finish even
+// if the client that caused it disconnects, bounded only by the database timeout
+func BackgroundDBContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), dbTimeout)
+}
+
+// EnsureIndexes - creates the indexes missing from a collection, logging which were
+// created and which already existed. Every index must be given a name to compare by.
+func EnsureIndexes(coll *mongo.Collection, indexes []mongo.IndexModel) error {
+	ctx, cancel := BackgroundDBContext()
+	defer cancel()
+
+	cursor, err := coll.Indexes().List(ctx)
+	if err != nil {
+		return err
+	}
+	var specs []bson.M
+	if err := cursor.All(ctx, &specs); err != nil {
+		return err
+	}
+	existing := map[string]bool{}
+	for _, spec := range specs {
+		if name, ok := spec["name"].(string); ok {
+			existing[name] = true
+		}
+	}
+
+	var missing []mongo.IndexModel
+	for _, index := range indexes {
+		name := *index.Options.Name
+		if existing[name] {
+			log.Printf("Index %s.%s already exists", coll.Name(), name)
+			continue
+		}
+		missing = append(missing, index)
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	names, err := coll.Indexes().CreateMany(ctx, missing)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		log.Printf("Created index %s.%s", coll.Name(), name)
+	}
+	return nil
+}
+
+// duplicateKeyCode - server error code for a unique index violation
+const duplicateKeyCode = 11000
+
+// IsDuplicateKey - reports whether a write failed because of a unique index
+func IsDuplicateKey(err error) bool {
+	if we, ok := err.(mongo.WriteException); ok {
+		for _, e := range we.WriteErrors {
+			if e.Code == duplicateKeyCode {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 //////////
 // TESTING
 //////////
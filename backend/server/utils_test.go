@@ -0,0 +1,84 @@
+package utility
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestParsePagination(t *testing.T) {
+	SetPageLimits(10, 20)
+	defer SetPageLimits(DEFAULT_PAGE_LIMIT, MAX_PAGE_LIMIT)
+	parse := func(query string) (int64, int64, error) {
+		req := httptest.NewRequest(http.MethodGet, "/"+query, nil)
+		return ParsePagination(echo.New().NewContext(req, httptest.NewRecorder()))
+	}
+
+	t.Run("Default limit is used without a limit", func(t *testing.T) {
+		limit, offset, err := parse("")
+		if err != nil || limit != 10 || offset != 0 {
+			t.Errorf("Expected limit 10 and offset 0, got %d, %d, %v", limit, offset, err)
+		}
+	})
+
+	t.Run("Limit is clamped to the maximum", func(t *testing.T) {
+		limit, offset, err := parse("?limit=1000&offset=5")
+		if err != nil || limit != 20 || offset != 5 {
+			t.Errorf("Expected limit 20 and offset 5, got %d, %d, %v", limit, offset, err)
+		}
+	})
+
+	t.Run("Invalid limits are rejected", func(t *testing.T) {
+		for _, query := range []string{"?limit=0", "?limit=ten", "?offset=-1"} {
+			if _, _, err := parse(query); err == nil {
+				t.Errorf("Expected %s to be rejected", query)
+			}
+		}
+	})
+}
+
+func TestIsDuplicateKey(t *testing.T) {
+	t.Run("Unique index violations are duplicates", func(t *testing.T) {
+		err := mongo.WriteException{
+			WriteErrors: mongo.WriteErrors{{Code: duplicateKeyCode, Message: "E11000 duplicate key error"}},
+		}
+		if !IsDuplicateKey(err) {
+			t.Errorf("Expected a duplicate key error")
+		}
+	})
+
+	t.Run("Other write errors are not duplicates", func(t *testing.T) {
+		err := mongo.WriteException{
+			WriteErrors: mongo.WriteErrors{{Code: 121, Message: "Document failed validation"}},
+		}
+		if IsDuplicateKey(err) {
+			t.Errorf("Expected a validation error not to be a duplicate")
+		}
+		if IsDuplicateKey(errors.New("connection refused")) {
+			t.Errorf("Expected a network error not to be a duplicate")
+		}
+	})
+}
+
+func TestLogError(t *testing.T) {
+	t.Run("Errors are logged with the request ID", func(t *testing.T) {
+		var buf bytes.Buffer
+		e := echo.New()
+		e.Logger.SetOutput(&buf)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec)
+		c.Response().Header().Set(echo.HeaderXRequestID, "abc123")
+
+		LogError(c, errors.New("connection refused"))
+
+		if !strings.Contains(buf.String(), "Request abc123: connection refused") {
+			t.Errorf("Expected the request ID in the log, got %q", buf.String())
+		}
+	})
+}